@@ -0,0 +1,49 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/pingcap/go-ycsb/pkg/workload"
+	"github.com/spf13/cobra"
+)
+
+func runCheckCommandFunc(cmd *cobra.Command, args []string) {
+	f, err := os.Open(args[0])
+	if err != nil {
+		util.Fatalf("open history failed %v", err)
+	}
+	defer f.Close()
+
+	report, err := workload.CheckHistory(f)
+	if err != nil {
+		util.Fatalf("check history failed %v", err)
+	}
+	fmt.Printf("Consistency: %s\n", &report)
+	if report.AnomalyCount() > 0 {
+		os.Exit(1)
+	}
+}
+
+func newCheckCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "check history_file",
+		Short: "Check the history recorded by the consistency workload",
+		Args:  cobra.ExactArgs(1),
+		Run:   runCheckCommandFunc,
+	}
+}
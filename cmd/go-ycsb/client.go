@@ -15,12 +15,15 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/pingcap/go-ycsb/pkg/client"
 	"github.com/pingcap/go-ycsb/pkg/measurement"
 	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
 	"github.com/spf13/cobra"
 )
 
@@ -56,11 +59,45 @@ func runClientCommandFunc(cmd *cobra.Command, args []string, doTransactions bool
 	fmt.Println("**********************************************")
 
 	c := client.NewClient(globalProps, globalWorkload, globalDB)
+	serveStatus(c)
 	start := time.Now()
-	c.Run(globalContext)
+	err := c.Run(globalContext)
+	end := time.Now()
 	fmt.Println("**********************************************")
-	fmt.Printf("Run finished, takes %s\n", time.Now().Sub(start))
+	fmt.Printf("Run finished, takes %s\n", end.Sub(start))
 	measurement.Output()
+	exportResult(dbName, command, start, end)
+	if err != nil {
+		util.Fatalf("%s failed %v", command, err)
+	}
+}
+
+// exportResult exports the final result of the run, see the exporter and
+// exportfile properties.
+func exportResult(dbName string, command string, start time.Time, end time.Time) {
+	err := measurement.Export(&ycsb.Result{
+		Label:     globalProps.GetString(prop.Label, ""),
+		DB:        dbName,
+		Workload:  globalProps.GetString(prop.Workload, "core"),
+		Command:   command,
+		Version:   buildVersion(),
+		StartTime: start,
+		EndTime:   end,
+	})
+	if err != nil {
+		util.Fatalf("export result failed %v", err)
+	}
+}
+
+// serveStatus serves the status API of the client on the debug.pprof server
+// if the status property is set.
+func serveStatus(c *client.Client) {
+	if !globalProps.GetBool(prop.Status, false) {
+		return
+	}
+	h := c.StatusHandler()
+	http.Handle(client.StatusPath, h)
+	http.Handle(client.StatusPath+"/", h)
 }
 
 func runLoadCommandFunc(cmd *cobra.Command, args []string) {
@@ -0,0 +1,127 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/go-ycsb/pkg/distributed"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentAddr  string
+	agentAddrs []string
+)
+
+func runAgentCommandFunc(cmd *cobra.Command, args []string) {
+	fmt.Printf("Agent listening on %s\n", agentAddr)
+	if err := http.ListenAndServe(agentAddr, distributed.NewAgent().Handler()); err != nil {
+		util.Fatalf("agent failed %v", err)
+	}
+}
+
+func runCoordinatorCommandFunc(cmd *cobra.Command, args []string, doTransactions bool, command string) {
+	dbName := args[0]
+
+	initialProperties(func() {
+		globalProps.Set(prop.DoTransactions, strconv.FormatBool(doTransactions))
+		globalProps.Set(prop.Command, command)
+
+		if len(tableName) > 0 {
+			globalProps.Set(prop.TableName, tableName)
+		}
+
+		if cmd.Flags().Changed("threads") {
+			globalProps.Set(prop.ThreadCount, strconv.Itoa(threadsArg))
+		}
+
+		if cmd.Flags().Changed("target") {
+			globalProps.Set(prop.Target, strconv.Itoa(targetArg))
+		}
+
+		if cmd.Flags().Changed("interval") {
+			globalProps.Set(prop.LogInterval, strconv.Itoa(reportInterval))
+		}
+	})
+
+	if len(agentAddrs) == 0 {
+		util.Fatalf("no agent is specified")
+	}
+
+	fmt.Println("***************** properties *****************")
+	for key, value := range globalProps.Map() {
+		fmt.Printf("\"%s\"=\"%s\"\n", key, value)
+	}
+	fmt.Println("**********************************************")
+
+	c := distributed.NewCoordinator(globalProps, dbName, agentAddrs)
+	start := time.Now()
+	if err := c.Run(globalContext); err != nil {
+		util.Fatalf("%s failed %v", command, err)
+	}
+	end := time.Now()
+	fmt.Println("**********************************************")
+	fmt.Printf("Run finished, takes %s\n", end.Sub(start))
+	measurement.Output()
+	exportResult(dbName, command, start, end)
+}
+
+func newAgentCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "agent",
+		Short: "Run the benchmark driven by a coordinator",
+		Args:  cobra.NoArgs,
+		Run:   runAgentCommandFunc,
+	}
+
+	m.Flags().StringVar(&agentAddr, "listen", ":6061", "Address to listen for the coordinator")
+	return m
+}
+
+func newCoordinatorCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "coordinator",
+		Short: "Drive the benchmark from many agents and merge their measurements",
+	}
+
+	for _, c := range []struct {
+		use            string
+		short          string
+		doTransactions bool
+	}{
+		{"load", "YCSB load benchmark on the agents", false},
+		{"run", "YCSB run benchmark on the agents", true},
+	} {
+		c := c
+		sub := &cobra.Command{
+			Use:   c.use + " db",
+			Short: c.short,
+			Args:  cobra.MinimumNArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				runCoordinatorCommandFunc(cmd, args, c.doTransactions, c.use)
+			},
+		}
+		initClientCommand(sub)
+		sub.Flags().StringSliceVar(&agentAddrs, "agents", nil, "Addresses of the agents, separated by commas")
+		m.AddCommand(sub)
+	}
+	return m
+}
@@ -94,7 +94,7 @@ var (
 	globalProps    *properties.Properties
 )
 
-func initialGlobal(dbName string, onProperties func()) {
+func initialProperties(onProperties func()) {
 	globalProps = properties.NewProperties()
 	if len(propertyFiles) > 0 {
 		globalProps = properties.MustLoadFiles(propertyFiles, properties.UTF8, false)
@@ -113,11 +113,16 @@ func initialGlobal(dbName string, onProperties func()) {
 	}
 
 	addr := globalProps.GetString(prop.DebugPprof, prop.DebugPprofDefault)
+	http.Handle(measurement.PrometheusPath, measurement.PrometheusHandler())
 	go func() {
 		http.ListenAndServe(addr, nil)
 	}()
 
 	measurement.InitMeasure(globalProps)
+}
+
+func initialGlobal(dbName string, onProperties func()) {
+	initialProperties(onProperties)
 
 	if len(tableName) == 0 {
 		tableName = globalProps.GetString(prop.TableName, prop.TableNameDefault)
@@ -142,7 +147,9 @@ func initialGlobal(dbName string, onProperties func()) {
 	if globalDB, err = dbCreator.Create(globalProps); err != nil {
 		util.Fatalf("create db %s failed %v", dbName, err)
 	}
-	globalDB = client.DbWrapper{globalDB}
+	if globalDB, err = client.WrapDB(globalProps, globalDB); err != nil {
+		util.Fatalf("wrap db %s failed %v", dbName, err)
+	}
 }
 
 func main() {
@@ -183,6 +190,10 @@ func main() {
 		newShellCommand(),
 		newLoadCommand(),
 		newRunCommand(),
+		newSearchCommand(),
+		newAgentCommand(),
+		newCoordinatorCommand(),
+		newCheckCommand(),
 	)
 
 	cobra.EnablePrefixMatching = true
@@ -0,0 +1,111 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/go-ycsb/pkg/client"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var searchHeader = []string{"Trial", "Target", "Throughput", "Latency(us)", "Pass"}
+
+func runSearchCommandFunc(cmd *cobra.Command, args []string) {
+	dbName := args[0]
+
+	initialGlobal(dbName, func() {
+		globalProps.Set(prop.DoTransactions, "true")
+		globalProps.Set(prop.Command, "search")
+
+		if cmd.Flags().Changed("threads") {
+			globalProps.Set(prop.ThreadCount, strconv.Itoa(threadsArg))
+		}
+
+		if cmd.Flags().Changed("interval") {
+			globalProps.Set(prop.LogInterval, strconv.Itoa(reportInterval))
+		}
+	})
+
+	fmt.Println("***************** properties *****************")
+	for key, value := range globalProps.Map() {
+		fmt.Printf("\"%s\"=\"%s\"\n", key, value)
+	}
+	fmt.Println("**********************************************")
+
+	c := client.NewClient(globalProps, globalWorkload, globalDB)
+	serveStatus(c)
+	start := time.Now()
+	trials, err := c.Search(globalContext)
+	fmt.Println("**********************************************")
+	fmt.Printf("Search finished, takes %s\n", time.Now().Sub(start))
+	if err != nil {
+		util.Fatalf("search failed %v", err)
+	}
+
+	var best *client.SearchTrial
+	lines := make([][]string, 0, len(trials))
+	for i := range trials {
+		t := &trials[i]
+		if t.Pass && (best == nil || t.Throughput > best.Throughput) {
+			best = t
+		}
+		lines = append(lines, []string{
+			strconv.Itoa(i + 1),
+			strconv.FormatInt(t.Target, 10),
+			util.FloatToOneString(t.Throughput),
+			strconv.FormatInt(t.Latency, 10),
+			strconv.FormatBool(t.Pass),
+		})
+	}
+
+	outputStyle := globalProps.GetString(prop.OutputStyle, util.OutputStylePlain)
+	switch outputStyle {
+	case util.OutputStylePlain:
+		util.RenderString(os.Stdout, "Trial %s - %s\n", searchHeader, lines)
+	case util.OutputStyleJson:
+		util.RenderJson(os.Stdout, searchHeader, lines)
+	case util.OutputStyleTable:
+		util.RenderTable(os.Stdout, searchHeader, lines)
+	default:
+		util.Fatalf("unsupported outputstyle: %s", outputStyle)
+	}
+
+	if best == nil {
+		fmt.Println("No trial meets the latency SLO")
+		return
+	}
+	fmt.Printf("Max throughput under the latency SLO: %.1f ops/sec (target %d ops/sec, %s p%v %dus)\n",
+		best.Throughput, best.Target,
+		globalProps.GetString(prop.SearchOperation, prop.SearchOperationDefault),
+		globalProps.GetFloat64(prop.SearchPercentile, prop.SearchPercentileDefault),
+		best.Latency)
+}
+
+func newSearchCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "search db",
+		Short: "Search the max throughput under a latency SLO",
+		Args:  cobra.MinimumNArgs(1),
+		Run:   runSearchCommandFunc,
+	}
+
+	initClientCommand(m)
+	return m
+}
@@ -39,6 +39,7 @@ import (
 
 	"github.com/magiconair/properties"
 	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
 	"github.com/pingcap/go-ycsb/pkg/ycsb"
 )
 
@@ -61,6 +62,7 @@ type basicState struct {
 
 // BasicDB just prints out the requested operations, instead of doing them against a database
 type basicDB struct {
+	p              *properties.Properties
 	verbose        bool
 	randomizeDelay bool
 	toDelay        int64
@@ -86,9 +88,9 @@ func (db *basicDB) delay(ctx context.Context, state *basicState) {
 	}
 }
 
-func (db *basicDB) InitThread(ctx context.Context, _ int, _ int) context.Context {
+func (db *basicDB) InitThread(ctx context.Context, threadID int, _ int) context.Context {
 	state := new(basicState)
-	state.r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	state.r = util.NewThreadRand(db.p, "basic", threadID)
 	state.buf = new(bytes.Buffer)
 
 	return context.WithValue(ctx, stateKey, state)
@@ -258,6 +260,7 @@ type basicDBCreator struct{}
 
 func (basicDBCreator) Create(p *properties.Properties) (ycsb.DB, error) {
 	db := new(basicDB)
+	db.p = p
 
 	db.verbose = p.GetBool(prop.Verbose, prop.VerboseDefault)
 	db.randomizeDelay = p.GetBool(randomizeDelay, randomizeDelayDefault)
@@ -17,6 +17,7 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -52,6 +53,51 @@ type elastic struct {
 	bi        esutil.BulkIndexer
 	indexName string
 	verbose   bool
+
+	// keywords are the fields mapped as keywords, which are stored as
+	// strings instead of base64 for the term and range queries.
+	keywordsMu sync.RWMutex
+	keywords   map[string]bool
+}
+
+// encode returns the document of the values, the keyword fields are strings.
+func (m *elastic) encode(values map[string][]byte) map[string]interface{} {
+	m.keywordsMu.RLock()
+	defer m.keywordsMu.RUnlock()
+
+	doc := make(map[string]interface{}, len(values))
+	for field, value := range values {
+		if m.keywords[field] {
+			doc[field] = string(value)
+		} else {
+			doc[field] = value
+		}
+	}
+	return doc
+}
+
+// decode returns the values of the document encoded by encode.
+func (m *elastic) decode(doc map[string]json.RawMessage) (map[string][]byte, error) {
+	m.keywordsMu.RLock()
+	defer m.keywordsMu.RUnlock()
+
+	values := make(map[string][]byte, len(doc))
+	for field, raw := range doc {
+		if m.keywords[field] {
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, err
+			}
+			values[field] = []byte(value)
+			continue
+		}
+		var value []byte
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		values[field] = value
+	}
+	return values, nil
 }
 
 func (m *elastic) Close() error {
@@ -88,7 +134,7 @@ func (m *elastic) Scan(ctx context.Context, table string, startKey string, count
 
 // Insert a document.
 func (m *elastic) Insert(ctx context.Context, table string, key string, values map[string][]byte) error {
-	data, err := json.Marshal(values)
+	data, err := json.Marshal(m.encode(values))
 	if err != nil {
 		if m.verbose {
 			fmt.Println("Cannot encode document %d: %s", key, err)
@@ -132,7 +178,7 @@ func (m *elastic) Insert(ctx context.Context, table string, key string, values m
 
 // Update a document.
 func (m *elastic) Update(ctx context.Context, table string, key string, values map[string][]byte) error {
-	data, err := json.Marshal(values)
+	data, err := json.Marshal(m.encode(values))
 	if err != nil {
 		if m.verbose {
 			fmt.Println("Cannot encode document %d: %s", key, err)
@@ -208,6 +254,85 @@ func (m *elastic) Delete(ctx context.Context, table string, key string) error {
 	return nil
 }
 
+// CreateIndex maps a field as a keyword, which is indexed for the exact
+// values and their order. The index must be recreated by load before the
+// field is mapped.
+func (m *elastic) CreateIndex(ctx context.Context, table string, field string) error {
+	m.keywordsMu.Lock()
+	m.keywords[field] = true
+	m.keywordsMu.Unlock()
+
+	mapping := map[string]interface{}{"properties": map[string]interface{}{field: map[string]interface{}{"type": "keyword"}}}
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	res, err := m.cli.Indices.PutMapping([]string{m.indexName}, bytes.NewReader(data), m.cli.Indices.PutMapping.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("cannot map field %s: %s", field, res)
+	}
+	return nil
+}
+
+// Query documents by the value of a keyword field, whose strings are in the
+// order of the bytes.
+func (m *elastic) Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) ([]map[string][]byte, error) {
+	predicate := map[string]interface{}{"term": map[string]interface{}{field: string(low)}}
+	if !bytes.Equal(low, high) {
+		predicate = map[string]interface{}{"range": map[string]interface{}{
+			field: map[string]interface{}{"gte": string(low), "lte": string(high)},
+		}}
+	}
+	search := map[string]interface{}{"query": predicate}
+	if len(fields) > 0 {
+		search["_source"] = fields
+	}
+	data, err := json.Marshal(search)
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.cli.Search(
+		m.cli.Search.WithContext(ctx),
+		m.cli.Search.WithIndex(m.indexName),
+		m.cli.Search.WithBody(bytes.NewReader(data)),
+		m.cli.Search.WithSize(count),
+	)
+	if err != nil {
+		if m.verbose {
+			fmt.Printf("Cannot query %s: %s\n", field, err)
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("cannot query %s: %s", field, res)
+	}
+
+	var r struct {
+		Hits struct {
+			Hits []struct {
+				Source map[string]json.RawMessage `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	docs := make([]map[string][]byte, 0, len(r.Hits.Hits))
+	for _, hit := range r.Hits.Hits {
+		doc, err := m.decode(hit.Source)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
 type elasticCreator struct {
 }
 
@@ -337,10 +462,53 @@ func (c elasticCreator) Create(p *properties.Properties) (ycsb.DB, error) {
 		bi:        bi,
 		indexName: iname,
 		verbose:   verbose,
+		keywords:  make(map[string]bool),
+	}
+	// the run doesn't create the indexes, the keyword fields are mapped by
+	// the load.
+	if strings.Compare("load", command) != 0 {
+		if err := m.loadKeywords(); err != nil {
+			return nil, err
+		}
 	}
 	return m, nil
 }
 
+// loadKeywords finds the fields mapped as keywords in the mapping of the
+// index, which doesn't exist before the first load.
+func (m *elastic) loadKeywords() error {
+	res, err := m.cli.Indices.GetMapping(m.cli.Indices.GetMapping.WithIndex(m.indexName))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if res.IsError() {
+		return fmt.Errorf("cannot get the mapping of index %s: %s", m.indexName, res)
+	}
+
+	var r map[string]struct {
+		Mappings struct {
+			Properties map[string]struct {
+				Type string `json:"type"`
+			} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return err
+	}
+	for _, index := range r {
+		for field, mapping := range index.Mappings.Properties {
+			if mapping.Type == "keyword" {
+				m.keywords[field] = true
+			}
+		}
+	}
+	return nil
+}
+
 func init() {
 	ycsb.RegisterDBCreator("elastic", elasticCreator{})
 }
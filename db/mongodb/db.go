@@ -3,6 +3,7 @@
 package mongodb
 
 import (
+	"bytes"
 	"context"
 	"crypto/x509"
 	"errors"
@@ -123,6 +124,43 @@ func (m *mongoDB) Delete(ctx context.Context, table string, key string) error {
 	return nil
 }
 
+// CreateIndex creates an ascending index of a field, it succeeds if the
+// index exists.
+func (m *mongoDB) CreateIndex(ctx context.Context, table string, field string) error {
+	model := mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}}}
+	if _, err := m.db.Collection(table).Indexes().CreateOne(ctx, model); err != nil {
+		return fmt.Errorf("CreateIndex error: %s", err.Error())
+	}
+	return nil
+}
+
+// Query documents by the value of a field.
+func (m *mongoDB) Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) ([]map[string][]byte, error) {
+	projection := map[string]bool{"_id": false}
+	for _, f := range fields {
+		projection[f] = true
+	}
+	limit := int64(count)
+	opt := &options.FindOptions{Projection: projection, Limit: &limit}
+
+	filter := bson.M{field: low}
+	if !bytes.Equal(low, high) {
+		filter = bson.M{field: bson.M{"$gte": low, "$lte": high}}
+	}
+	cursor, err := m.db.Collection(table).Find(ctx, filter, opt)
+	if err != nil {
+		return nil, fmt.Errorf("Query error: %s", err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var docs []map[string][]byte
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
 type mongodbCreator struct{}
 
 func (c mongodbCreator) Create(p *properties.Properties) (ycsb.DB, error) {
@@ -20,6 +20,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
 	"sync/atomic"
@@ -91,6 +92,9 @@ type contextKey string
 
 const stateKey = contextKey("mysqlDB")
 
+// txnKey is the key of the transaction begun by Begin in the context.
+const txnKey = contextKey("mysqlDBTxn")
+
 type mysqlState struct {
 	// Do we need a LRU cache here?
 	stmtCache map[string]*sql.Stmt
@@ -187,6 +191,28 @@ func (db *mysqlDB) Close() error {
 	return db.db.Close()
 }
 
+// ClassifyError classifies the errors of MySQL and TiDB by their numbers.
+func (db *mysqlDB) ClassifyError(err error) ycsb.ErrorClass {
+	var e *mysql.MySQLError
+	if !errors.As(err, &e) {
+		return ycsb.ErrorOther
+	}
+
+	switch e.Number {
+	case 1213, 8002, 8022, 9007:
+		// deadlock, and the write conflicts of TiDB
+		return ycsb.ErrorConflict
+	case 1205, 9001, 9002:
+		// lock wait timeout, and the PD or TiKV server timeout of TiDB
+		return ycsb.ErrorTimeout
+	case 1040, 9003:
+		// too many connections, and the TiKV server is busy
+		return ycsb.ErrorThrottled
+	default:
+		return ycsb.ErrorOther
+	}
+}
+
 func (db *mysqlDB) InitThread(ctx context.Context, _ int, _ int) context.Context {
 	conn, err := db.db.Conn(ctx)
 	if err != nil {
@@ -210,6 +236,36 @@ func (db *mysqlDB) CleanupThread(ctx context.Context) {
 	state.conn.Close()
 }
 
+// Begin implements the ycsb.TransactionalDB Begin interface, the transaction
+// runs on the connection of the thread.
+func (db *mysqlDB) Begin(ctx context.Context) (context.Context, error) {
+	state := ctx.Value(stateKey).(*mysqlState)
+
+	tx, err := state.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, txnKey, tx), nil
+}
+
+// Commit implements the ycsb.TransactionalDB Commit interface.
+func (db *mysqlDB) Commit(ctx context.Context) error {
+	tx, ok := ctx.Value(txnKey).(*sql.Tx)
+	if !ok {
+		return ycsb.ErrNoTransaction
+	}
+	return tx.Commit()
+}
+
+// Abort implements the ycsb.TransactionalDB Abort interface.
+func (db *mysqlDB) Abort(ctx context.Context) error {
+	tx, ok := ctx.Value(txnKey).(*sql.Tx)
+	if !ok {
+		return ycsb.ErrNoTransaction
+	}
+	return tx.Rollback()
+}
+
 func (db *mysqlDB) getAndCacheStmt(ctx context.Context, query string) (*sql.Stmt, error) {
 	state := ctx.Value(stateKey).(*mysqlState)
 
@@ -245,12 +301,27 @@ func (db *mysqlDB) clearCacheIfFailed(ctx context.Context, query string, err err
 	delete(state.stmtCache, query)
 }
 
+// getStmt returns the cached statement of the query, which runs in the
+// transaction of ctx if it is begun by Begin.
+func (db *mysqlDB) getStmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := db.getAndCacheStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx, ok := ctx.Value(txnKey).(*sql.Tx); ok {
+		// the statement is closed when the transaction ends.
+		stmt = tx.StmtContext(ctx, stmt)
+	}
+	return stmt, nil
+}
+
 func (db *mysqlDB) queryRows(ctx context.Context, query string, count int, args ...interface{}) ([]map[string][]byte, error) {
 	if db.verbose {
 		fmt.Printf("%s %v\n", query, args)
 	}
 
-	stmt, err := db.getAndCacheStmt(ctx, query)
+	stmt, err := db.getStmt(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -357,7 +428,7 @@ func (db *mysqlDB) execQuery(ctx context.Context, query string, args ...interfac
 		fmt.Printf("%s %v\n", query, args)
 	}
 
-	stmt, err := db.getAndCacheStmt(ctx, query)
+	stmt, err := db.getStmt(ctx, query)
 	if err != nil {
 		return err
 	}
@@ -500,6 +571,46 @@ func (db *mysqlDB) BatchDelete(ctx context.Context, table string, keys []string)
 	return db.execQuery(ctx, string(buf[:]), args...)
 }
 
+// CreateIndex implements the ycsb.QueryDB CreateIndex interface.
+func (db *mysqlDB) CreateIndex(ctx context.Context, table string, field string) error {
+	query := fmt.Sprintf(`CREATE INDEX %s_%s_idx ON %s (%s)`, table, field, table, field)
+	if db.verbose {
+		fmt.Println(query)
+	}
+
+	_, err := db.db.ExecContext(ctx, query)
+	var e *mysql.MySQLError
+	if errors.As(err, &e) && e.Number == 1061 {
+		// duplicate key name, the index exists
+		return nil
+	}
+	return err
+}
+
+// Query implements the ycsb.QueryDB Query interface.
+func (db *mysqlDB) Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) ([]map[string][]byte, error) {
+	columns := "*"
+	if len(fields) > 0 {
+		columns = strings.Join(fields, ",")
+	}
+
+	var (
+		query string
+		rows  []map[string][]byte
+		err   error
+	)
+	if bytes.Equal(low, high) {
+		query = fmt.Sprintf(`SELECT %s FROM %s WHERE %s = ? LIMIT ?`, columns, table, field)
+		rows, err = db.queryRows(ctx, query, count, low, count)
+	} else {
+		query = fmt.Sprintf(`SELECT %s FROM %s WHERE %s BETWEEN ? AND ? LIMIT ?`, columns, table, field)
+		rows, err = db.queryRows(ctx, query, count, low, high, count)
+	}
+	db.clearCacheIfFailed(ctx, query, err)
+
+	return rows, err
+}
+
 func (db *mysqlDB) Analyze(ctx context.Context, table string) error {
 	_, err := db.db.Exec(fmt.Sprintf(`ANALYZE TABLE %s`, table))
 	return err
@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -24,7 +25,7 @@ import (
 	"github.com/pingcap/go-ycsb/pkg/util"
 
 	// pg package
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/magiconair/properties"
 	"github.com/pingcap/go-ycsb/pkg/ycsb"
 )
@@ -57,6 +58,9 @@ type contextKey string
 
 const stateKey = contextKey("pgDB")
 
+// txnKey is the key of the transaction begun by Begin in the context.
+const txnKey = contextKey("pgDBTxn")
+
 type pgState struct {
 	// Do we need a LRU cache here?
 	stmtCache map[string]*sql.Stmt
@@ -130,6 +134,28 @@ func (db *pgDB) createTable() error {
 	return err
 }
 
+// ClassifyError classifies the errors of PostgreSQL by their SQLSTATE codes.
+func (db *pgDB) ClassifyError(err error) ycsb.ErrorClass {
+	var e *pq.Error
+	if !errors.As(err, &e) {
+		return ycsb.ErrorOther
+	}
+
+	switch e.Code {
+	case "40001", "40P01":
+		// serialization failure and deadlock
+		return ycsb.ErrorConflict
+	case "57014", "55P03":
+		// query canceled by the statement timeout and lock not available
+		return ycsb.ErrorTimeout
+	case "53300":
+		// too many connections
+		return ycsb.ErrorThrottled
+	default:
+		return ycsb.ErrorOther
+	}
+}
+
 func (db *pgDB) Close() error {
 	if db.db == nil {
 		return nil
@@ -161,6 +187,36 @@ func (db *pgDB) CleanupThread(ctx context.Context) {
 	state.conn.Close()
 }
 
+// Begin implements the ycsb.TransactionalDB Begin interface, the transaction
+// runs on the connection of the thread.
+func (db *pgDB) Begin(ctx context.Context) (context.Context, error) {
+	state := ctx.Value(stateKey).(*pgState)
+
+	tx, err := state.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, txnKey, tx), nil
+}
+
+// Commit implements the ycsb.TransactionalDB Commit interface.
+func (db *pgDB) Commit(ctx context.Context) error {
+	tx, ok := ctx.Value(txnKey).(*sql.Tx)
+	if !ok {
+		return ycsb.ErrNoTransaction
+	}
+	return tx.Commit()
+}
+
+// Abort implements the ycsb.TransactionalDB Abort interface.
+func (db *pgDB) Abort(ctx context.Context) error {
+	tx, ok := ctx.Value(txnKey).(*sql.Tx)
+	if !ok {
+		return ycsb.ErrNoTransaction
+	}
+	return tx.Rollback()
+}
+
 func (db *pgDB) getAndCacheStmt(ctx context.Context, query string) (*sql.Stmt, error) {
 	state := ctx.Value(stateKey).(*pgState)
 
@@ -196,12 +252,27 @@ func (db *pgDB) clearCacheIfFailed(ctx context.Context, query string, err error)
 	delete(state.stmtCache, query)
 }
 
+// getStmt returns the cached statement of the query, which runs in the
+// transaction of ctx if it is begun by Begin.
+func (db *pgDB) getStmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := db.getAndCacheStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx, ok := ctx.Value(txnKey).(*sql.Tx); ok {
+		// the statement is closed when the transaction ends.
+		stmt = tx.StmtContext(ctx, stmt)
+	}
+	return stmt, nil
+}
+
 func (db *pgDB) queryRows(ctx context.Context, query string, count int, args ...interface{}) ([]map[string][]byte, error) {
 	if db.verbose {
 		fmt.Printf("%s %v\n", query, args)
 	}
 
-	stmt, err := db.getAndCacheStmt(ctx, query)
+	stmt, err := db.getStmt(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -277,7 +348,7 @@ func (db *pgDB) execQuery(ctx context.Context, query string, args ...interface{}
 		fmt.Printf("%s %v\n", query, args)
 	}
 
-	stmt, err := db.getAndCacheStmt(ctx, query)
+	stmt, err := db.getStmt(ctx, query)
 	if err != nil {
 		return err
 	}
@@ -353,6 +424,41 @@ func (db *pgDB) Delete(ctx context.Context, table string, key string) error {
 	return db.execQuery(ctx, query, key)
 }
 
+// CreateIndex implements the ycsb.QueryDB CreateIndex interface.
+func (db *pgDB) CreateIndex(ctx context.Context, table string, field string) error {
+	query := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s)`, table, field, table, field)
+	if db.verbose {
+		fmt.Println(query)
+	}
+
+	_, err := db.db.ExecContext(ctx, query)
+	return err
+}
+
+// Query implements the ycsb.QueryDB Query interface.
+func (db *pgDB) Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) ([]map[string][]byte, error) {
+	columns := "*"
+	if len(fields) > 0 {
+		columns = strings.Join(fields, ",")
+	}
+
+	var (
+		query string
+		rows  []map[string][]byte
+		err   error
+	)
+	if bytes.Equal(low, high) {
+		query = fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 LIMIT $2`, columns, table, field)
+		rows, err = db.queryRows(ctx, query, count, low, count)
+	} else {
+		query = fmt.Sprintf(`SELECT %s FROM %s WHERE %s BETWEEN $1 AND $2 LIMIT $3`, columns, table, field)
+		rows, err = db.queryRows(ctx, query, count, low, high, count)
+	}
+	db.clearCacheIfFailed(ctx, query, err)
+
+	return rows, err
+}
+
 func init() {
 	ycsb.RegisterDBCreator("pg", pgCreator{})
 	ycsb.RegisterDBCreator("postgresql", pgCreator{})
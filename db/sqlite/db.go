@@ -393,8 +393,45 @@ func (db *sqliteDB) BatchDelete(ctx context.Context, table string, keys []string
 	})
 }
 
+// CreateIndex implements the ycsb.QueryDB CreateIndex interface.
+func (db *sqliteDB) CreateIndex(ctx context.Context, table string, field string) error {
+	query := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s)`, table, field, table, field)
+	if db.verbose {
+		fmt.Println(query)
+	}
+
+	_, err := db.db.ExecContext(ctx, query)
+	return err
+}
+
+func (db *sqliteDB) doQuery(ctx context.Context, tx *sql.Tx, table string, field string, low []byte, high []byte, count int, fields []string) ([]map[string][]byte, error) {
+	columns := "*"
+	if len(fields) > 0 {
+		columns = strings.Join(fields, ",")
+	}
+
+	if bytes.Equal(low, high) {
+		query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = ? LIMIT ?`, columns, table, field)
+		return db.doQueryRows(ctx, tx, query, count, low, count)
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s BETWEEN ? AND ? LIMIT ?`, columns, table, field)
+	return db.doQueryRows(ctx, tx, query, count, low, high, count)
+}
+
+// Query implements the ycsb.QueryDB Query interface.
+func (db *sqliteDB) Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) ([]map[string][]byte, error) {
+	var output []map[string][]byte
+	err := db.optimisticTx(ctx, func(tx *sql.Tx) error {
+		res, err := db.doQuery(ctx, tx, table, field, low, high, count, fields)
+		output = res
+		return err
+	})
+	return output, err
+}
+
 func init() {
 	ycsb.RegisterDBCreator("sqlite", sqliteCreator{})
 }
 
 var _ ycsb.BatchDB = (*sqliteDB)(nil)
+var _ ycsb.QueryDB = (*sqliteDB)(nil)
@@ -14,11 +14,13 @@
 package tikv
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/magiconair/properties"
 	"github.com/pingcap/go-ycsb/pkg/ycsb"
 	"github.com/tikv/client-go/v2/config"
+	tikverr "github.com/tikv/client-go/v2/error"
 )
 
 const (
@@ -50,6 +52,25 @@ func (c tikvCreator) Create(p *properties.Properties) (ycsb.DB, error) {
 	}
 }
 
+// classifyError classifies the errors of the TiKV client.
+func classifyError(err error) ycsb.ErrorClass {
+	var latchConflict *tikverr.ErrWriteConflictInLatch
+	var retryable *tikverr.ErrRetryable
+	switch {
+	case tikverr.IsErrNotFound(err):
+		return ycsb.ErrorNotFound
+	case tikverr.IsErrWriteConflict(err), errors.As(err, &latchConflict), errors.As(err, &retryable):
+		return ycsb.ErrorConflict
+	case errors.Is(err, tikverr.ErrTiKVServerTimeout), errors.Is(err, tikverr.ErrLockWaitTimeout),
+		errors.Is(err, tikverr.ErrResolveLockTimeout):
+		return ycsb.ErrorTimeout
+	case errors.Is(err, tikverr.ErrTiKVServerBusy):
+		return ycsb.ErrorThrottled
+	default:
+		return ycsb.ErrorOther
+	}
+}
+
 func init() {
 	ycsb.RegisterDBCreator("tikv", tikvCreator{})
 }
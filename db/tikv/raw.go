@@ -58,6 +58,10 @@ func (db *rawDB) Close() error {
 	return db.db.Close()
 }
 
+func (db *rawDB) ClassifyError(err error) ycsb.ErrorClass {
+	return classifyError(err)
+}
+
 func (db *rawDB) InitThread(ctx context.Context, _ int, _ int) context.Context {
 	return ctx
 }
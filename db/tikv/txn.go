@@ -70,6 +70,10 @@ func (db *txnDB) Close() error {
 	return db.db.Close()
 }
 
+func (db *txnDB) ClassifyError(err error) ycsb.ErrorClass {
+	return classifyError(err)
+}
+
 func (db *txnDB) InitThread(ctx context.Context, _ int, _ int) context.Context {
 	return ctx
 }
@@ -93,8 +97,76 @@ func (db *txnDB) beginTxn() (*transaction.KVTxn, error) {
 	return txn, err
 }
 
+// txnKey is the key of the transaction begun by Begin in the context.
+type txnKey struct{}
+
+// Begin implements the ycsb.TransactionalDB Begin interface, the operations
+// with the returned context run in the transaction until Commit or Abort.
+func (db *txnDB) Begin(ctx context.Context) (context.Context, error) {
+	tx, err := db.beginTxn()
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, txnKey{}, tx), nil
+}
+
+// Commit implements the ycsb.TransactionalDB Commit interface.
+func (db *txnDB) Commit(ctx context.Context) error {
+	tx, ok := ctx.Value(txnKey{}).(*transaction.KVTxn)
+	if !ok {
+		return ycsb.ErrNoTransaction
+	}
+	return tx.Commit(ctx)
+}
+
+// Abort implements the ycsb.TransactionalDB Abort interface.
+func (db *txnDB) Abort(ctx context.Context) error {
+	tx, ok := ctx.Value(txnKey{}).(*transaction.KVTxn)
+	if !ok {
+		return ycsb.ErrNoTransaction
+	}
+	return tx.Rollback()
+}
+
+// opTxn is the transaction of an operation, its Commit and Rollback do
+// nothing if it is the transaction begun by Begin, which is ended by Commit
+// or Abort instead.
+type opTxn struct {
+	*transaction.KVTxn
+	begun bool
+}
+
+func (tx opTxn) Commit(ctx context.Context) error {
+	if tx.begun {
+		return nil
+	}
+	return tx.KVTxn.Commit(ctx)
+}
+
+func (tx opTxn) Rollback() error {
+	if tx.begun {
+		return nil
+	}
+	return tx.KVTxn.Rollback()
+}
+
+// beginReadTxn begins the transaction of a read without the options of
+// beginTxn.
+func (db *txnDB) beginReadTxn() (*transaction.KVTxn, error) {
+	return db.db.Begin()
+}
+
+// txn returns the transaction of ctx begun by Begin, or a new one from begin.
+func (db *txnDB) txn(ctx context.Context, begin func() (*transaction.KVTxn, error)) (opTxn, error) {
+	if tx, ok := ctx.Value(txnKey{}).(*transaction.KVTxn); ok {
+		return opTxn{KVTxn: tx, begun: true}, nil
+	}
+	tx, err := begin()
+	return opTxn{KVTxn: tx}, err
+}
+
 func (db *txnDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
-	tx, err := db.db.Begin()
+	tx, err := db.txn(ctx, db.beginReadTxn)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +187,7 @@ func (db *txnDB) Read(ctx context.Context, table string, key string, fields []st
 }
 
 func (db *txnDB) BatchRead(ctx context.Context, table string, keys []string, fields []string) ([]map[string][]byte, error) {
-	tx, err := db.db.Begin()
+	tx, err := db.txn(ctx, db.beginReadTxn)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +210,7 @@ func (db *txnDB) BatchRead(ctx context.Context, table string, keys []string, fie
 }
 
 func (db *txnDB) Scan(ctx context.Context, table string, startKey string, count int, fields []string) ([]map[string][]byte, error) {
-	tx, err := db.db.Begin()
+	tx, err := db.txn(ctx, db.beginReadTxn)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +255,7 @@ func (db *txnDB) Scan(ctx context.Context, table string, startKey string, count
 func (db *txnDB) Update(ctx context.Context, table string, key string, values map[string][]byte) error {
 	rowKey := db.getRowKey(table, key)
 
-	tx, err := db.beginTxn()
+	tx, err := db.txn(ctx, db.beginTxn)
 	if err != nil {
 		return err
 	}
@@ -223,7 +295,7 @@ func (db *txnDB) Update(ctx context.Context, table string, key string, values ma
 }
 
 func (db *txnDB) BatchUpdate(ctx context.Context, table string, keys []string, values []map[string][]byte) error {
-	tx, err := db.beginTxn()
+	tx, err := db.txn(ctx, db.beginTxn)
 	if err != nil {
 		return err
 	}
@@ -254,7 +326,7 @@ func (db *txnDB) Insert(ctx context.Context, table string, key string, values ma
 		return err
 	}
 
-	tx, err := db.beginTxn()
+	tx, err := db.txn(ctx, db.beginTxn)
 	if err != nil {
 		return err
 	}
@@ -269,7 +341,7 @@ func (db *txnDB) Insert(ctx context.Context, table string, key string, values ma
 }
 
 func (db *txnDB) BatchInsert(ctx context.Context, table string, keys []string, values []map[string][]byte) error {
-	tx, err := db.beginTxn()
+	tx, err := db.txn(ctx, db.beginTxn)
 	if err != nil {
 		return err
 	}
@@ -288,7 +360,7 @@ func (db *txnDB) BatchInsert(ctx context.Context, table string, keys []string, v
 }
 
 func (db *txnDB) Delete(ctx context.Context, table string, key string) error {
-	tx, err := db.beginTxn()
+	tx, err := db.txn(ctx, db.beginTxn)
 	if err != nil {
 		return err
 	}
@@ -304,7 +376,7 @@ func (db *txnDB) Delete(ctx context.Context, table string, key string) error {
 }
 
 func (db *txnDB) BatchDelete(ctx context.Context, table string, keys []string) error {
-	tx, err := db.beginTxn()
+	tx, err := db.txn(ctx, db.beginTxn)
 	if err != nil {
 		return err
 	}
@@ -16,7 +16,6 @@ package client
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"os"
 	"sync"
 	"time"
@@ -24,24 +23,55 @@ import (
 	"github.com/magiconair/properties"
 	"github.com/pingcap/go-ycsb/pkg/measurement"
 	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
 	"github.com/pingcap/go-ycsb/pkg/ycsb"
 )
 
+type contextKey string
+
+const stateKey = contextKey("client")
+
+// Latency modes, see prop.MeasurementLatency.
+const (
+	latencyOp       = "op"
+	latencyIntended = "intended"
+	latencyBoth     = "both"
+)
+
+// threadState is the state of a worker shared with the DbWrapper through the context.
+type threadState struct {
+	latencyMode string
+	// intendedStart is the time when the current operation should have started
+	// following the target throughput schedule.
+	intendedStart time.Time
+}
+
 type worker struct {
-	p               *properties.Properties
-	workDB          ycsb.DB
-	workload        ycsb.Workload
-	doTransactions  bool
-	doBatch         bool
-	batchSize       int
-	opCount         int64
-	targetOpsPerMs  float64
-	threadID        int
-	targetOpsTickNs int64
-	opsDone         int64
+	p              *properties.Properties
+	workDB         ycsb.DB
+	workload       ycsb.Workload
+	doTransactions bool
+	doBatch        bool
+	batchSize      int
+	opCount        int64
+	threadID       int
+	threadCount    int
+	opsDone        int64
+	// control is changed through the status API.
+	control *control
+	// schedule is the target throughput of all workers, nil means unlimited.
+	schedule *schedule
+	// startTime is the start of the throttling schedule, it is set when the
+	// first operation after warm-up is issued. scheduleOps is the number of
+	// operations done before it, and startPausedFor the pause time of the run
+	// before it.
+	startTime      time.Time
+	scheduleOps    int64
+	startPausedFor time.Duration
+	state          *threadState
 }
 
-func newWorker(p *properties.Properties, threadID int, threadCount int, workload ycsb.Workload, db ycsb.DB) *worker {
+func newWorker(p *properties.Properties, threadID int, threadCount int, workload ycsb.Workload, db ycsb.DB, ctl *control) *worker {
 	w := new(worker)
 	w.p = p
 	w.doTransactions = p.GetBool(prop.DoTransactions, true)
@@ -50,8 +80,19 @@ func newWorker(p *properties.Properties, threadID int, threadCount int, workload
 		w.doBatch = true
 	}
 	w.threadID = threadID
+	w.threadCount = threadCount
+	w.control = ctl
+	w.schedule = ctl.load().schedule
 	w.workload = workload
 	w.workDB = db
+	w.state = &threadState{
+		latencyMode: p.GetString(prop.MeasurementLatency, prop.MeasurementLatencyDefault),
+	}
+	switch w.state.latencyMode {
+	case latencyOp, latencyIntended, latencyBoth:
+	default:
+		util.Fatalf("unsupported %s: %s", prop.MeasurementLatency, w.state.latencyMode)
+	}
 
 	var totalOpCount int64
 	if w.doTransactions {
@@ -64,7 +105,7 @@ func newWorker(p *properties.Properties, threadID int, threadCount int, workload
 		}
 	}
 
-	if totalOpCount < int64(threadCount) {
+	if !isUnbounded(p, w.doTransactions, totalOpCount) && totalOpCount < int64(threadCount) {
 		fmt.Printf("totalOpCount(%s/%s/%s): %d should be bigger than threadCount: %d",
 			prop.OperationCount,
 			prop.InsertCount,
@@ -80,45 +121,117 @@ func newWorker(p *properties.Properties, threadID int, threadCount int, workload
 		w.opCount++
 	}
 
-	targetPerThreadPerms := float64(-1)
-	if v := p.GetInt64(prop.Target, 0); v > 0 {
-		targetPerThread := float64(v) / float64(threadCount)
-		targetPerThreadPerms = targetPerThread / 1000.0
-	}
-
-	if targetPerThreadPerms > 0 {
-		w.targetOpsPerMs = targetPerThreadPerms
-		w.targetOpsTickNs = int64(1000000.0 / w.targetOpsPerMs)
-	}
-
 	return w
 }
 
-func (w *worker) throttle(ctx context.Context, startTime time.Time) {
-	if w.targetOpsPerMs <= 0 {
-		return
+// isUnbounded returns whether the run has no operation count, it stops only
+// when the context is cancelled, e.g. when maxexecutiontime is reached. An
+// unbounded run needs operationcount=0 set explicitly or maxexecutiontime, a
+// run missing operationcount is still an error.
+func isUnbounded(p *properties.Properties, doTransactions bool, totalOpCount int64) bool {
+	if !doTransactions || totalOpCount != 0 {
+		return false
 	}
+	_, explicit := p.Get(prop.OperationCount)
+	return explicit || p.GetInt64(prop.MaxExecutiontime, 0) > 0
+}
 
-	d := time.Duration(w.opsDone * w.targetOpsTickNs)
-	d = startTime.Add(d).Sub(time.Now())
-	if d < 0 {
-		return
+// follow follows the controls of the status API, it blocks while the run is
+// paused. When the target throughput changes, the worker restarts throttling
+// with the new schedule.
+func (w *worker) follow(ctx context.Context) *runState {
+	for {
+		st := w.control.load()
+		if st.resumed != nil {
+			select {
+			case <-ctx.Done():
+				return st
+			case <-st.resumed:
+			}
+			continue
+		}
+
+		if st.schedule != w.schedule {
+			w.schedule = st.schedule
+			w.startTime = time.Time{}
+		}
+		return st
 	}
-	select {
-	case <-ctx.Done():
-	case <-time.After(d):
+}
+
+// throttle waits until the intended start time of the next operation and
+// returns it. The intended start time is fixed by the target schedule no
+// matter how long the previous operations took, so the queueing delay of a
+// stalled DB is not hidden (coordinated omission).
+// It returns false if the schedule has finished.
+func (w *worker) throttle(ctx context.Context) (time.Time, bool) {
+	for {
+		st := w.follow(ctx)
+		now := time.Now()
+		if w.schedule == nil {
+			return now, true
+		}
+
+		if w.startTime.IsZero() {
+			w.startTime = now
+			w.scheduleOps = w.opsDone
+			w.startPausedFor = st.pausedFor
+		}
+
+		// every worker takes an even share of the target throughput.
+		offset, ok := w.schedule.offset(float64((w.opsDone - w.scheduleOps) * int64(w.threadCount)))
+		if !ok {
+			return now, false
+		}
+
+		// the schedule is delayed by the time the run has been paused.
+		intended := w.startTime.Add(offset + st.pausedFor - w.startPausedFor)
+		d := intended.Sub(now)
+		if d <= 0 {
+			return intended, true
+		}
+		select {
+		case <-ctx.Done():
+			return intended, true
+		case <-time.After(d):
+		}
+		// the run may be paused or get a new target while waiting, which
+		// delays or changes the schedule.
+		if w.control.load() == st {
+			return intended, true
+		}
 	}
 }
 
 func (w *worker) run(ctx context.Context) {
 	// spread the thread operation out so they don't all hit the DB at the same time
-	if w.targetOpsPerMs > 0.0 && w.targetOpsPerMs <= 1.0 {
-		time.Sleep(time.Duration(rand.Int63n(w.targetOpsTickNs)))
+	if w.schedule != nil {
+		targetPerThread := w.schedule.initialRate() / float64(w.threadCount)
+		if targetPerThread > 0 && targetPerThread <= 1000 {
+			r := util.NewThreadRand(w.p, "client", w.threadID)
+			time.Sleep(time.Duration(r.Int63n(int64(float64(time.Second) / targetPerThread))))
+		}
 	}
 
-	startTime := time.Now()
-
 	for w.opCount == 0 || w.opsDone < w.opCount {
+		// the operations during warm-up are neither throttled nor counted
+		measured := measurement.IsWarmUpFinished()
+		if measured {
+			var ok bool
+			if w.state.intendedStart, ok = w.throttle(ctx); !ok {
+				return
+			}
+		} else {
+			w.follow(ctx)
+			w.state.intendedStart = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
 		var err error
 		opsCount := 1
 		if w.doTransactions {
@@ -137,13 +250,15 @@ func (w *worker) run(ctx context.Context) {
 			}
 		}
 
+		if err == ycsb.ErrWorkloadFinished {
+			return
+		}
 		if err != nil && !w.p.GetBool(prop.Silence, prop.SilenceDefault) {
-			fmt.Printf("operation err: %v\n", err)
+			fmt.Printf("operation err (%s): %v\n", classifyError(w.workDB, err), err)
 		}
 
-		if measurement.IsWarmUpFinished() {
+		if measured {
 			w.opsDone += int64(opsCount)
-			w.throttle(ctx, startTime)
 		}
 
 		select {
@@ -159,18 +274,38 @@ type Client struct {
 	p        *properties.Properties
 	workload ycsb.Workload
 	db       ycsb.DB
+	control  *control
 }
 
 // NewClient returns a client with the given workload and DB.
 // The workload and db can't be nil.
 func NewClient(p *properties.Properties, workload ycsb.Workload, db ycsb.DB) *Client {
-	return &Client{p: p, workload: workload, db: db}
+	return &Client{p: p, workload: workload, db: db, control: newControl()}
 }
 
 // Run runs the workload to the target DB, and blocks until all workers end.
-func (c *Client) Run(ctx context.Context) {
+// It returns the error of the validation if the workload is a
+// ycsb.Validator.
+func (c *Client) Run(ctx context.Context) error {
+	return c.run(ctx, nil)
+}
+
+// run runs the workload, and calls finished if it is not nil once all
+// workers end, before the data is analyzed or validated.
+func (c *Client) run(ctx context.Context, finished func()) error {
 	var wg sync.WaitGroup
 	threadCount := c.p.GetInt(prop.ThreadCount, 1)
+	sch, err := newSchedule(c.p)
+	if err != nil {
+		util.Fatalf("invalid %s: %v", prop.TargetSchedule, err)
+	}
+
+	// runCtx is cancelled when maxexecutiontime is reached, it stops the workers
+	// but leaves the measurements of the completed operations intact.
+	runCtx, runCancel := context.WithCancel(ctx)
+	defer runCancel()
+	c.control.start(sch, runCancel)
+	defer c.control.finish()
 
 	wg.Add(threadCount)
 	measureCtx, measureCancel := context.WithCancel(ctx)
@@ -191,17 +326,83 @@ func (c *Client) Run(ctx context.Context) {
 		// finish warming up
 		measurement.EnableWarmUp(false)
 
+		// the execution time is counted from the end of warm-up, so that
+		// maxexecutiontime is the length of the measured steady state. Both
+		// it and the phases of the schedule don't count the pauses.
+		start := time.Now()
+		startPaused := c.control.load().paused(start)
+		elapsed := func() time.Duration {
+			now := time.Now()
+			return now.Sub(start) - (c.control.load().paused(now) - startPaused)
+		}
+
+		var deadline time.Duration
+		if maxExecutionTime := c.p.GetInt64(prop.MaxExecutiontime, 0); maxExecutionTime > 0 {
+			deadline = time.Duration(maxExecutionTime) * time.Second
+		}
+
+		// every phase of the schedule gets its own summary when it finishes.
+		var phases []phase
+		var phaseEnd time.Duration
+		if sch != nil && sch.phases[0].duration > 0 {
+			phases = sch.phases
+			measurement.StartPhase(phases[0].name)
+			phaseEnd = phases[0].duration
+		}
+		defer measurement.FinishPhase()
+
 		dur := c.p.GetInt64(prop.LogInterval, 10)
 		t := time.NewTicker(time.Duration(dur) * time.Second)
 		defer t.Stop()
 
 		for {
+			// the timers are stopped while the run is paused.
+			var wake <-chan time.Time
+			var timer *time.Timer
+			st := c.control.load()
+			if st.resumed == nil {
+				next := time.Duration(-1)
+				for _, end := range []time.Duration{deadline, phaseEnd} {
+					if end > 0 && (next < 0 || end < next) {
+						next = end
+					}
+				}
+				if next >= 0 {
+					timer = time.NewTimer(next - elapsed())
+					wake = timer.C
+				}
+			}
+
 			select {
 			case <-t.C:
 				measurement.Summary()
+			case <-st.resumed:
+			case <-wake:
+				now := elapsed()
+				if phaseEnd > 0 && now >= phaseEnd {
+					measurement.FinishPhase()
+					phases = phases[1:]
+					if len(phases) == 0 {
+						phaseEnd = 0
+					} else {
+						measurement.StartPhase(phases[0].name)
+						phaseEnd += phases[0].duration
+					}
+				}
+				if deadline > 0 && now >= deadline {
+					fmt.Println("Maximum execution time reached, stopping workers")
+					runCancel()
+					deadline = 0
+				}
 			case <-measureCtx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
 				return
 			}
+			if timer != nil {
+				timer.Stop()
+			}
 		}
 	}()
 
@@ -209,8 +410,10 @@ func (c *Client) Run(ctx context.Context) {
 		go func(threadId int) {
 			defer wg.Done()
 
-			w := newWorker(c.p, threadId, threadCount, c.workload, c.db)
-			ctx := c.workload.InitThread(ctx, threadId, threadCount)
+			w := newWorker(c.p, threadId, threadCount, c.workload, c.db, c.control)
+			ctx := context.WithValue(runCtx, stateKey, w.state)
+			ctx = measurement.InitThread(ctx)
+			ctx = c.workload.InitThread(ctx, threadId, threadCount)
 			ctx = c.db.InitThread(ctx, threadId, threadCount)
 			w.run(ctx)
 			c.db.CleanupThread(ctx)
@@ -219,12 +422,37 @@ func (c *Client) Run(ctx context.Context) {
 	}
 
 	wg.Wait()
+	if finished != nil {
+		finished()
+	}
+	var validateErr error
 	if !c.p.GetBool(prop.DoTransactions, true) {
 		// when loading is finished, try to analyze table if possible.
 		if analyzeDB, ok := c.db.(ycsb.AnalyzeDB); ok {
 			analyzeDB.Analyze(ctx, c.p.GetString(prop.TableName, prop.TableNameDefault))
 		}
+	} else if validator, ok := c.workload.(ycsb.Validator); ok {
+		validateErr = c.validate(ctx, validator)
 	}
 	measureCancel()
 	<-measureCh
+	return validateErr
+}
+
+// validate validates the data after the run with a thread of its own, its
+// reads are not measured as the operations of the run.
+func (c *Client) validate(ctx context.Context, validator ycsb.Validator) error {
+	measurement.EnableWarmUp(true)
+	defer measurement.EnableWarmUp(false)
+
+	ctx = c.db.InitThread(ctx, 0, 1)
+	defer c.db.CleanupThread(ctx)
+
+	fmt.Println("Validating the data")
+	if err := validator.Validate(ctx, c.db); err != nil {
+		fmt.Printf("Validation failed: %v\n", err)
+		return fmt.Errorf("validation failed %v", err)
+	}
+	fmt.Println("Validation passed")
+	return nil
 }
@@ -0,0 +1,284 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// sleepDB is a DB whose reads take a fixed time and respect the context.
+type sleepDB struct {
+	ycsb.DB
+	delay time.Duration
+}
+
+func (db sleepDB) InitThread(ctx context.Context, _ int, _ int) context.Context {
+	return ctx
+}
+
+func (db sleepDB) CleanupThread(_ context.Context) {
+}
+
+func (db sleepDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
+	select {
+	case <-time.After(db.delay):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+var errBusy = errors.New("server is busy")
+
+// failDB is a DB whose reads return the errors in order and succeed after
+// them, it classifies errBusy itself.
+type failDB struct {
+	ycsb.DB
+	errs []error
+}
+
+func (db *failDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
+	if len(db.errs) == 0 {
+		return nil, nil
+	}
+	err := db.errs[0]
+	db.errs = db.errs[1:]
+	return nil, err
+}
+
+func (db *failDB) ClassifyError(err error) ycsb.ErrorClass {
+	if errors.Is(err, errBusy) {
+		return ycsb.ErrorThrottled
+	}
+	return ycsb.ErrorOther
+}
+
+// readWorkload issues one read per transaction.
+type readWorkload struct {
+	ycsb.Workload
+}
+
+func (readWorkload) InitThread(ctx context.Context, _ int, _ int) context.Context {
+	return ctx
+}
+
+func (readWorkload) CleanupThread(_ context.Context) {
+}
+
+func (readWorkload) DoTransaction(ctx context.Context, db ycsb.DB) error {
+	_, err := db.Read(ctx, "t", "k", nil)
+	return err
+}
+
+func newTestProperties(t *testing.T, kvs ...string) *properties.Properties {
+	p := properties.NewProperties()
+	p.Set(prop.OutputStyle, "json")
+	p.Set(prop.MeasurementRawOutputFile, filepath.Join(t.TempDir(), "output.json"))
+	for i := 0; i+1 < len(kvs); i += 2 {
+		p.Set(kvs[i], kvs[i+1])
+	}
+	return p
+}
+
+// outputRows writes the final measurement output and returns the row of every operation.
+func outputRows(t *testing.T, p *properties.Properties) map[string]map[string]string {
+	measurement.Output()
+	data, err := os.ReadFile(p.GetString(prop.MeasurementRawOutputFile, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []map[string]string
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("bad output %q: %v", data, err)
+	}
+	res := make(map[string]map[string]string, len(rows))
+	for _, row := range rows {
+		res[row["Operation"]] = row
+	}
+	return res
+}
+
+func rowValue(t *testing.T, rows map[string]map[string]string, op string, column string) int64 {
+	row, ok := rows[op]
+	if !ok {
+		t.Fatalf("operation %s is not reported: %v", op, rows)
+	}
+	v, err := strconv.ParseFloat(row[column], 64)
+	if err != nil {
+		t.Fatalf("bad %s of %s: %v", column, op, err)
+	}
+	return int64(v)
+}
+
+func TestMaxExecutionTime(t *testing.T) {
+	p := newTestProperties(t,
+		prop.ThreadCount, "4",
+		prop.OperationCount, "0",
+		prop.MaxExecutiontime, "1",
+	)
+	measurement.InitMeasure(p)
+
+	db := DbWrapper{sleepDB{delay: 30 * time.Millisecond}}
+	c := NewClient(p, readWorkload{}, db)
+
+	start := time.Now()
+	c.Run(context.Background())
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 5*time.Second {
+		t.Fatalf("run should stop after about 1s, but takes %s", elapsed)
+	}
+
+	rows := outputRows(t, p)
+	if rowValue(t, rows, "READ", "Count") == 0 {
+		t.Fatalf("no completed reads are reported: %v", rows)
+	}
+	if _, ok := rows["READ_ERROR"]; ok {
+		t.Fatalf("reads interrupted by the deadline must not be reported: %v", rows)
+	}
+}
+
+func TestUnbounded(t *testing.T) {
+	p := properties.NewProperties()
+	if isUnbounded(p, true, 0) {
+		t.Fatal("a run without operationcount must not be unbounded")
+	}
+
+	p.Set(prop.MaxExecutiontime, "10")
+	if !isUnbounded(p, true, 0) {
+		t.Fatal("a run bounded by maxexecutiontime can be unbounded")
+	}
+	if isUnbounded(p, false, 0) {
+		t.Fatal("a load must not be unbounded")
+	}
+
+	p = properties.NewProperties()
+	p.Set(prop.OperationCount, "0")
+	if !isUnbounded(p, true, 0) {
+		t.Fatal("operationcount=0 asks for an unbounded run")
+	}
+}
+
+func TestIntendedLatency(t *testing.T) {
+	// the DB takes 20ms for each read while the target asks for one read
+	// every 5ms, so the operations queue up behind each other.
+	p := newTestProperties(t,
+		prop.ThreadCount, "1",
+		prop.OperationCount, "30",
+		prop.Target, "200",
+		prop.MeasurementLatency, "both",
+	)
+	measurement.InitMeasure(p)
+
+	db := DbWrapper{sleepDB{delay: 20 * time.Millisecond}}
+	NewClient(p, readWorkload{}, db).Run(context.Background())
+
+	rows := outputRows(t, p)
+	if count := rowValue(t, rows, "READ_INTENDED", "Count"); count != 30 {
+		t.Fatalf("want 30 intended reads, but got %d", count)
+	}
+	opMax := rowValue(t, rows, "READ", "Max(us)")
+	intendedMax := rowValue(t, rows, "READ_INTENDED", "Max(us)")
+	if opMax > 100000 {
+		t.Fatalf("op latency should not include the queueing delay, but got %dus", opMax)
+	}
+	// the last read is scheduled at 145ms but starts at about 580ms.
+	if intendedMax < 300000 {
+		t.Fatalf("intended latency should include the queueing delay, but got %dus", intendedMax)
+	}
+}
+
+func TestTargetSchedule(t *testing.T) {
+	p := newTestProperties(t,
+		prop.ThreadCount, "2",
+		prop.OperationCount, "0",
+		prop.TargetSchedule, "slow:500ms:100,fast:500ms:200-400",
+	)
+	measurement.InitMeasure(p)
+
+	db := DbWrapper{sleepDB{}}
+	start := time.Now()
+	NewClient(p, readWorkload{}, db).Run(context.Background())
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond || elapsed > 3*time.Second {
+		t.Fatalf("run should stop after the last phase, but takes %s", elapsed)
+	}
+
+	// 50 reads in the first phase and 150 reads in the second one.
+	if count := rowValue(t, outputRows(t, p), "READ", "Count"); count < 190 || count > 200 {
+		t.Fatalf("want about 200 reads, but got %d", count)
+	}
+}
+
+func TestErrorClasses(t *testing.T) {
+	p := newTestProperties(t)
+	measurement.InitMeasure(p)
+
+	db := DbWrapper{&failDB{errs: []error{
+		ycsb.WrapError(ycsb.ErrorConflict, errors.New("write conflict")),
+		context.DeadlineExceeded,
+		errBusy,
+		errors.New("unknown"),
+		errBusy,
+	}}}
+	for i := 0; i < 5; i++ {
+		if _, err := db.Read(context.Background(), "t", "k", nil); err == nil {
+			t.Fatal("read should fail")
+		}
+	}
+
+	rows := outputRows(t, p)
+	for op, want := range map[string]int64{
+		"READ_ERROR":           5,
+		"READ_ERROR_CONFLICT":  1,
+		"READ_ERROR_TIMEOUT":   1,
+		"READ_ERROR_THROTTLED": 2,
+		"READ_ERROR_OTHER":     1,
+	} {
+		if count := rowValue(t, rows, op, "Count"); count != want {
+			t.Fatalf("want %d of %s, but got %d", want, op, count)
+		}
+	}
+}
+
+// invalidWorkload fails the validation after the run.
+type invalidWorkload struct {
+	readWorkload
+}
+
+func (invalidWorkload) Validate(ctx context.Context, db ycsb.DB) error {
+	return errors.New("total balance changed")
+}
+
+func TestValidationFailure(t *testing.T) {
+	p := newTestProperties(t, prop.OperationCount, "10")
+	measurement.InitMeasure(p)
+
+	if err := NewClient(p, invalidWorkload{}, sleepDB{}).Run(context.Background()); err == nil {
+		t.Fatal("the failed validation should fail the run")
+	}
+	if err := NewClient(p, readWorkload{}, sleepDB{}).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
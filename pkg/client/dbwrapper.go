@@ -27,15 +27,58 @@ type DbWrapper struct {
 	DB ycsb.DB
 }
 
-func measure(start time.Time, op string, err error) {
+// ClassifyError returns the class of the error returned by the DB, see
+// ycsb.ClassifyError and ycsb.ErrorClassifier.
+func (db DbWrapper) ClassifyError(err error) ycsb.ErrorClass {
+	return classifyError(db.DB, err)
+}
+
+// classifyError returns the class of the error returned by db. The class
+// given to ycsb.WrapError and the timeouts take precedence over the
+// classifier of db.
+func classifyError(db ycsb.DB, err error) ycsb.ErrorClass {
+	class := ycsb.ClassifyError(err)
+	if c, ok := db.(ycsb.ErrorClassifier); ok && class == ycsb.ErrorOther {
+		class = c.ClassifyError(err)
+	}
+	return class
+}
+
+func (db DbWrapper) measure(ctx context.Context, start time.Time, op string, err error) {
 	lan := time.Now().Sub(start)
 	if err != nil {
-		measurement.Measure(fmt.Sprintf("%s_ERROR", op), start, lan)
+		// the operation was interrupted because the run is stopping, it is
+		// not a completed operation so don't report it.
+		if ctx.Err() != nil {
+			return
+		}
+
+		// the errors are measured as a whole and by their classes.
+		measurement.MeasureContext(ctx, fmt.Sprintf("%s_ERROR", op), start, lan)
+		measurement.MeasureContext(ctx, fmt.Sprintf("%s_ERROR_%s", op, db.ClassifyError(err)), start, lan)
+		return
+	}
+
+	state, ok := ctx.Value(stateKey).(*threadState)
+	if !ok || state.latencyMode == latencyOp || state.intendedStart.IsZero() {
+		measurement.MeasureContext(ctx, op, start, lan)
+		measurement.MeasureContext(ctx, "TOTAL", start, lan)
 		return
 	}
 
-	measurement.Measure(op, start, lan)
-	measurement.Measure("TOTAL", start, lan)
+	// the intended latency includes the time the operation waited behind the
+	// previous ones since its scheduled start.
+	intendedLan := lan + start.Sub(state.intendedStart)
+	if state.latencyMode == latencyIntended {
+		measurement.MeasureContext(ctx, op, state.intendedStart, intendedLan)
+		measurement.MeasureContext(ctx, "TOTAL", state.intendedStart, intendedLan)
+		return
+	}
+
+	measurement.MeasureContext(ctx, op, start, lan)
+	measurement.MeasureContext(ctx, "TOTAL", start, lan)
+	measurement.MeasureContext(ctx, fmt.Sprintf("%s_INTENDED", op), state.intendedStart, intendedLan)
+	measurement.MeasureContext(ctx, "TOTAL_INTENDED", state.intendedStart, intendedLan)
 }
 
 func (db DbWrapper) Close() error {
@@ -53,7 +96,7 @@ func (db DbWrapper) CleanupThread(ctx context.Context) {
 func (db DbWrapper) Read(ctx context.Context, table string, key string, fields []string) (_ map[string][]byte, err error) {
 	start := time.Now()
 	defer func() {
-		measure(start, "READ", err)
+		db.measure(ctx, start, "READ", err)
 	}()
 
 	return db.DB.Read(ctx, table, key, fields)
@@ -64,7 +107,7 @@ func (db DbWrapper) BatchRead(ctx context.Context, table string, keys []string,
 	if ok {
 		start := time.Now()
 		defer func() {
-			measure(start, "BATCH_READ", err)
+			db.measure(ctx, start, "BATCH_READ", err)
 		}()
 		return batchDB.BatchRead(ctx, table, keys, fields)
 	}
@@ -80,7 +123,7 @@ func (db DbWrapper) BatchRead(ctx context.Context, table string, keys []string,
 func (db DbWrapper) Scan(ctx context.Context, table string, startKey string, count int, fields []string) (_ []map[string][]byte, err error) {
 	start := time.Now()
 	defer func() {
-		measure(start, "SCAN", err)
+		db.measure(ctx, start, "SCAN", err)
 	}()
 
 	return db.DB.Scan(ctx, table, startKey, count, fields)
@@ -89,7 +132,7 @@ func (db DbWrapper) Scan(ctx context.Context, table string, startKey string, cou
 func (db DbWrapper) Update(ctx context.Context, table string, key string, values map[string][]byte) (err error) {
 	start := time.Now()
 	defer func() {
-		measure(start, "UPDATE", err)
+		db.measure(ctx, start, "UPDATE", err)
 	}()
 
 	return db.DB.Update(ctx, table, key, values)
@@ -100,7 +143,7 @@ func (db DbWrapper) BatchUpdate(ctx context.Context, table string, keys []string
 	if ok {
 		start := time.Now()
 		defer func() {
-			measure(start, "BATCH_UPDATE", err)
+			db.measure(ctx, start, "BATCH_UPDATE", err)
 		}()
 		return batchDB.BatchUpdate(ctx, table, keys, values)
 	}
@@ -116,7 +159,7 @@ func (db DbWrapper) BatchUpdate(ctx context.Context, table string, keys []string
 func (db DbWrapper) Insert(ctx context.Context, table string, key string, values map[string][]byte) (err error) {
 	start := time.Now()
 	defer func() {
-		measure(start, "INSERT", err)
+		db.measure(ctx, start, "INSERT", err)
 	}()
 
 	return db.DB.Insert(ctx, table, key, values)
@@ -127,7 +170,7 @@ func (db DbWrapper) BatchInsert(ctx context.Context, table string, keys []string
 	if ok {
 		start := time.Now()
 		defer func() {
-			measure(start, "BATCH_INSERT", err)
+			db.measure(ctx, start, "BATCH_INSERT", err)
 		}()
 		return batchDB.BatchInsert(ctx, table, keys, values)
 	}
@@ -143,7 +186,7 @@ func (db DbWrapper) BatchInsert(ctx context.Context, table string, keys []string
 func (db DbWrapper) Delete(ctx context.Context, table string, key string) (err error) {
 	start := time.Now()
 	defer func() {
-		measure(start, "DELETE", err)
+		db.measure(ctx, start, "DELETE", err)
 	}()
 
 	return db.DB.Delete(ctx, table, key)
@@ -154,7 +197,7 @@ func (db DbWrapper) BatchDelete(ctx context.Context, table string, keys []string
 	if ok {
 		start := time.Now()
 		defer func() {
-			measure(start, "BATCH_DELETE", err)
+			db.measure(ctx, start, "BATCH_DELETE", err)
 		}()
 		return batchDB.BatchDelete(ctx, table, keys)
 	}
@@ -173,3 +216,59 @@ func (db DbWrapper) Analyze(ctx context.Context, table string) error {
 	}
 	return nil
 }
+
+func (db DbWrapper) Begin(ctx context.Context) (_ context.Context, err error) {
+	txnDB, ok := db.DB.(ycsb.TransactionalDB)
+	if !ok {
+		return nil, ycsb.ErrNotTransactional
+	}
+	start := time.Now()
+	defer func() {
+		db.measure(ctx, start, "BEGIN", err)
+	}()
+	return txnDB.Begin(ctx)
+}
+
+func (db DbWrapper) Commit(ctx context.Context) (err error) {
+	txnDB, ok := db.DB.(ycsb.TransactionalDB)
+	if !ok {
+		return ycsb.ErrNotTransactional
+	}
+	start := time.Now()
+	defer func() {
+		db.measure(ctx, start, "COMMIT", err)
+	}()
+	return txnDB.Commit(ctx)
+}
+
+func (db DbWrapper) Abort(ctx context.Context) (err error) {
+	txnDB, ok := db.DB.(ycsb.TransactionalDB)
+	if !ok {
+		return ycsb.ErrNotTransactional
+	}
+	start := time.Now()
+	defer func() {
+		db.measure(ctx, start, "ABORT", err)
+	}()
+	return txnDB.Abort(ctx)
+}
+
+func (db DbWrapper) CreateIndex(ctx context.Context, table string, field string) error {
+	queryDB, ok := db.DB.(ycsb.QueryDB)
+	if !ok {
+		return ycsb.ErrNotQueryable
+	}
+	return queryDB.CreateIndex(ctx, table, field)
+}
+
+func (db DbWrapper) Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) (_ []map[string][]byte, err error) {
+	queryDB, ok := db.DB.(ycsb.QueryDB)
+	if !ok {
+		return nil, ycsb.ErrNotQueryable
+	}
+	start := time.Now()
+	defer func() {
+		db.measure(ctx, start, "QUERY", err)
+	}()
+	return queryDB.Query(ctx, table, field, low, high, count, fields)
+}
@@ -0,0 +1,320 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// faultyRandKey carries the rand of the worker, which draws the faults.
+const faultyRandKey = contextKey("faulty")
+
+var (
+	errInjected        = errors.New("injected error")
+	errInjectedTimeout = errors.New("injected timeout")
+	errInjectedHang    = errors.New("injected hang")
+	errInjectedBatch   = errors.New("injected partial batch failure")
+)
+
+var faultyOps = []string{
+	"READ", "BATCH_READ", "SCAN", "UPDATE", "BATCH_UPDATE",
+	"INSERT", "BATCH_INSERT", "DELETE", "BATCH_DELETE",
+	"BEGIN", "COMMIT", "ABORT", "QUERY",
+}
+
+// faults are the faults injected into an operation type, the rates are
+// the probabilities in [0, 1].
+type faults struct {
+	errorRate     float64
+	latencyRate   float64
+	latency       time.Duration
+	timeoutRate   float64
+	timeout       time.Duration
+	hangRate      float64
+	hang          time.Duration
+	batchFailRate float64
+}
+
+// faultWindow is a window of the schedule since the FaultyDB is created.
+type faultWindow struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// FaultyDB injects errors, latency spikes, timeouts, hung calls and partial
+// batch failures into the operations of DB. The faults are configured by
+// faulty.<fault>, or faulty.<op>.<fault> for an operation type, e.g.
+// faulty.read.errorrate, and injected only within the windows of
+// faulty.schedule if it is set.
+type FaultyDB struct {
+	MiddlewareDB
+
+	p          *properties.Properties
+	start      time.Time
+	windows    []faultWindow
+	errorClass ycsb.ErrorClass
+	faults     map[string]*faults
+}
+
+// NewFaultyDB creates the FaultyDB with the faulty properties.
+func NewFaultyDB(p *properties.Properties, db ycsb.DB) (*FaultyDB, error) {
+	f := &FaultyDB{
+		p:      p,
+		start:  time.Now(),
+		faults: make(map[string]*faults, len(faultyOps)),
+	}
+
+	class, ok := parseErrorClass(p.GetString(prop.FaultyErrorClass, prop.FaultyErrorClassDefault))
+	if !ok {
+		return nil, fmt.Errorf("unknown error class in %s", prop.FaultyErrorClass)
+	}
+	f.errorClass = class
+
+	var err error
+	if f.windows, err = parseFaultSchedule(p.GetString(prop.FaultySchedule, "")); err != nil {
+		return nil, err
+	}
+
+	for _, op := range faultyOps {
+		get := func(name string, def float64) float64 {
+			v := p.GetFloat64("faulty."+name, def)
+			return p.GetFloat64("faulty."+strings.ToLower(op)+"."+name, v)
+		}
+		f.faults[op] = &faults{
+			errorRate:     get("errorrate", 0),
+			latencyRate:   get("latencyrate", 0),
+			latency:       time.Duration(get("latency", 1000)) * time.Millisecond,
+			timeoutRate:   get("timeoutrate", 0),
+			timeout:       time.Duration(get("timeout", 5000)) * time.Millisecond,
+			hangRate:      get("hangrate", 0),
+			hang:          time.Duration(get("hang", 60000)) * time.Millisecond,
+			batchFailRate: get("batchfailrate", 0),
+		}
+	}
+
+	f.MiddlewareDB = MiddlewareDB{DB: db, Around: f.inject}
+	return f, nil
+}
+
+// parseFaultSchedule parses the windows in the format of start-end, e.g.
+// 60s-120s,5m-6m.
+func parseFaultSchedule(s string) ([]faultWindow, error) {
+	var windows []faultWindow
+	for _, w := range strings.Split(s, ",") {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+
+		bounds := strings.Split(w, "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid fault window %q, want start-end", w)
+		}
+		start, err := time.ParseDuration(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault window %q: %v", w, err)
+		}
+		end, err := time.ParseDuration(bounds[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault window %q: %v", w, err)
+		}
+		if end <= start {
+			return nil, fmt.Errorf("invalid fault window %q, the end must be after the start", w)
+		}
+		windows = append(windows, faultWindow{start: start, end: end})
+	}
+	return windows, nil
+}
+
+// active returns the faults of the operation, or nil if no fault is injected
+// at the moment.
+func (db *FaultyDB) active(op string) *faults {
+	f, ok := db.faults[op]
+	if !ok {
+		return nil
+	}
+	if len(db.windows) == 0 {
+		return f
+	}
+
+	elapsed := time.Now().Sub(db.start)
+	for _, w := range db.windows {
+		if elapsed >= w.start && elapsed < w.end {
+			return f
+		}
+	}
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// InitThread gives the worker its own rand to draw the faults, which follows
+// randomseed.
+func (db *FaultyDB) InitThread(ctx context.Context, threadID int, threadCount int) context.Context {
+	ctx = context.WithValue(ctx, faultyRandKey, util.NewThreadRand(db.p, "faulty", threadID))
+	return db.MiddlewareDB.InitThread(ctx, threadID, threadCount)
+}
+
+// rand returns the rand of the worker, or a new one if the context is not
+// initialized by InitThread.
+func (db *FaultyDB) rand(ctx context.Context) *rand.Rand {
+	if r, ok := ctx.Value(faultyRandKey).(*rand.Rand); ok {
+		return r
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+func (db *FaultyDB) inject(ctx context.Context, op *Op, f func() error) error {
+	fs := db.active(op.Name)
+	if fs == nil {
+		return f()
+	}
+
+	r := db.rand(ctx)
+	if r.Float64() < fs.hangRate {
+		if err := sleepContext(ctx, fs.hang); err != nil {
+			return err
+		}
+		return ycsb.WrapError(ycsb.ErrorTimeout, errInjectedHang)
+	}
+	if r.Float64() < fs.timeoutRate {
+		if err := sleepContext(ctx, fs.timeout); err != nil {
+			return err
+		}
+		return ycsb.WrapError(ycsb.ErrorTimeout, errInjectedTimeout)
+	}
+	if r.Float64() < fs.latencyRate {
+		if err := sleepContext(ctx, fs.latency); err != nil {
+			return err
+		}
+	}
+	if r.Float64() < fs.errorRate {
+		return ycsb.WrapError(db.errorClass, errInjected)
+	}
+	return f()
+}
+
+// partial returns how many keys of the batch to apply before it fails, or
+// false if the batch doesn't fail. The batch isn't issued if no key is
+// applied, since an empty batch is invalid on some DBs.
+func (db *FaultyDB) partial(ctx context.Context, op string, keys int) (int, bool) {
+	fs := db.active(op)
+	if fs == nil || keys == 0 {
+		return 0, false
+	}
+	r := db.rand(ctx)
+	if r.Float64() >= fs.batchFailRate {
+		return 0, false
+	}
+	return r.Intn(keys), true
+}
+
+func (db *FaultyDB) BatchRead(ctx context.Context, table string, keys []string, fields []string) ([]map[string][]byte, error) {
+	if n, ok := db.partial(ctx, "BATCH_READ", len(keys)); ok {
+		if n > 0 {
+			if _, err := db.MiddlewareDB.BatchRead(ctx, table, keys[:n], fields); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ycsb.WrapError(db.errorClass, errInjectedBatch)
+	}
+	return db.MiddlewareDB.BatchRead(ctx, table, keys, fields)
+}
+
+func (db *FaultyDB) BatchUpdate(ctx context.Context, table string, keys []string, values []map[string][]byte) error {
+	if n, ok := db.partial(ctx, "BATCH_UPDATE", len(keys)); ok {
+		if n > 0 {
+			if err := db.MiddlewareDB.BatchUpdate(ctx, table, keys[:n], values[:n]); err != nil {
+				return err
+			}
+		}
+		return ycsb.WrapError(db.errorClass, errInjectedBatch)
+	}
+	return db.MiddlewareDB.BatchUpdate(ctx, table, keys, values)
+}
+
+func (db *FaultyDB) BatchInsert(ctx context.Context, table string, keys []string, values []map[string][]byte) error {
+	if n, ok := db.partial(ctx, "BATCH_INSERT", len(keys)); ok {
+		if n > 0 {
+			if err := db.MiddlewareDB.BatchInsert(ctx, table, keys[:n], values[:n]); err != nil {
+				return err
+			}
+		}
+		return ycsb.WrapError(db.errorClass, errInjectedBatch)
+	}
+	return db.MiddlewareDB.BatchInsert(ctx, table, keys, values)
+}
+
+func (db *FaultyDB) BatchDelete(ctx context.Context, table string, keys []string) error {
+	if n, ok := db.partial(ctx, "BATCH_DELETE", len(keys)); ok {
+		if n > 0 {
+			if err := db.MiddlewareDB.BatchDelete(ctx, table, keys[:n]); err != nil {
+				return err
+			}
+		}
+		return ycsb.WrapError(db.errorClass, errInjectedBatch)
+	}
+	return db.MiddlewareDB.BatchDelete(ctx, table, keys)
+}
+
+// Begin, Commit and Abort are not run through Around by MiddlewareDB, so
+// their faults are injected here.
+func (db *FaultyDB) Begin(ctx context.Context) (txnCtx context.Context, err error) {
+	err = db.inject(ctx, &Op{Name: "BEGIN"}, func() (err error) {
+		txnCtx, err = db.MiddlewareDB.Begin(ctx)
+		return err
+	})
+	return txnCtx, err
+}
+
+func (db *FaultyDB) Commit(ctx context.Context) error {
+	return db.inject(ctx, &Op{Name: "COMMIT"}, func() error {
+		return db.MiddlewareDB.Commit(ctx)
+	})
+}
+
+func (db *FaultyDB) Abort(ctx context.Context) error {
+	return db.inject(ctx, &Op{Name: "ABORT"}, func() error {
+		return db.MiddlewareDB.Abort(ctx)
+	})
+}
+
+type faultyCreator struct{}
+
+func (faultyCreator) Create(p *properties.Properties, db ycsb.DB) (ycsb.DB, error) {
+	return NewFaultyDB(p, db)
+}
+
+func init() {
+	ycsb.RegisterDBMiddlewareCreator("faulty", faultyCreator{})
+}
@@ -0,0 +1,186 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+func TestFaultyDB(t *testing.T) {
+	p := newTestProperties(t,
+		"faulty.read.errorrate", "1",
+		prop.FaultyErrorClass, "conflict",
+		"faulty.batch_read.batchfailrate", "1",
+		"faulty.scan.hangrate", "1",
+	)
+	inner := &failDB{errs: make([]error, 10)}
+	db, err := NewFaultyDB(p, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := db.Read(ctx, "t", "k", nil); ycsb.ClassifyError(err) != ycsb.ErrorConflict {
+		t.Fatalf("want an injected conflict, but got %v", err)
+	}
+	if len(inner.errs) != 10 {
+		t.Fatal("the failed read must not reach the DB")
+	}
+
+	// the batch runs as the single reads which fail, so only disable the
+	// read errors to see the partial batch.
+	db.faults["READ"].errorRate = 0
+	if _, err := db.BatchRead(ctx, "t", make([]string, 5), nil); !errors.Is(err, errInjectedBatch) {
+		t.Fatalf("want a partial batch failure, but got %v", err)
+	}
+	if n := 10 - len(inner.errs); n >= 5 {
+		t.Fatalf("the partial batch should read less than 5 keys, but reads %d", n)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := db.Scan(ctx, "t", "k", 10, nil); err != context.DeadlineExceeded {
+		t.Fatalf("the hung scan should return with the context, but got %v", err)
+	}
+
+	// the hang is bounded for the runs which are never cancelled.
+	db.faults["SCAN"].hang = time.Millisecond
+	if _, err := db.Scan(context.Background(), "t", "k", 10, nil); !errors.Is(err, errInjectedHang) {
+		t.Fatalf("want the hung scan to time out, but got %v", err)
+	}
+}
+
+// batchDB counts the batch inserts, its other batches are not called.
+type batchDB struct {
+	sleepDB
+	ycsb.BatchDB
+	batches int
+}
+
+func (db *batchDB) BatchInsert(ctx context.Context, table string, keys []string, values []map[string][]byte) error {
+	db.batches++
+	return nil
+}
+
+func TestFaultyEmptyBatch(t *testing.T) {
+	p := newTestProperties(t, "faulty.batch_insert.batchfailrate", "1")
+	inner := new(batchDB)
+	db, err := NewFaultyDB(p, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a batch of one key fails before applying any key.
+	err = db.BatchInsert(context.Background(), "t", []string{"k"}, []map[string][]byte{nil})
+	if !errors.Is(err, errInjectedBatch) {
+		t.Fatalf("want a partial batch failure, but got %v", err)
+	}
+	if inner.batches != 0 {
+		t.Fatalf("the empty batch must not reach the DB, but got %d batches", inner.batches)
+	}
+}
+
+func TestFaultyTransaction(t *testing.T) {
+	p := newTestProperties(t,
+		"faulty.commit.errorrate", "1",
+		prop.FaultyErrorClass, "conflict",
+	)
+	inner := new(txnDB)
+	db, err := NewFaultyDB(p, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := db.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Commit(ctx); ycsb.ClassifyError(err) != ycsb.ErrorConflict {
+		t.Fatalf("want an injected conflict, but got %v", err)
+	}
+	if err := db.Abort(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if inner.commits != 0 || inner.aborts != 1 {
+		t.Fatalf("want 0 commits and 1 abort, but got %d and %d", inner.commits, inner.aborts)
+	}
+}
+
+func TestFaultyQuery(t *testing.T) {
+	p := newTestProperties(t, "faulty.query.errorrate", "1")
+	db, err := NewFaultyDB(p, queryDB{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Query(context.Background(), "t", "f", nil, nil, 1, nil); !errors.Is(err, errInjected) {
+		t.Fatalf("want an injected error, but got %v", err)
+	}
+}
+
+func TestFaultRandomSeed(t *testing.T) {
+	draw := func() []bool {
+		p := newTestProperties(t,
+			prop.RandomSeed, "1",
+			"faulty.errorrate", "0.5",
+		)
+		db, err := NewFaultyDB(p, sleepDB{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := db.InitThread(context.Background(), 0, 1)
+		failed := make([]bool, 100)
+		for i := range failed {
+			_, err := db.Read(ctx, "t", "k", nil)
+			failed[i] = err != nil
+		}
+		return failed
+	}
+
+	first, second := draw(), draw()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("the faults should follow randomseed, but the %d-th read differs", i)
+		}
+	}
+}
+
+func TestFaultSchedule(t *testing.T) {
+	p := newTestProperties(t,
+		prop.FaultySchedule, "1h-2h",
+		"faulty.errorrate", "1",
+	)
+	db, err := NewFaultyDB(p, &failDB{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read(context.Background(), "t", "k", nil); err != nil {
+		t.Fatalf("no fault is injected out of the schedule, but got %v", err)
+	}
+
+	db.start = time.Now().Add(-time.Hour)
+	if _, err := db.Read(context.Background(), "t", "k", nil); !errors.Is(err, errInjected) {
+		t.Fatalf("want an injected error in the schedule, but got %v", err)
+	}
+
+	p.Set(prop.FaultySchedule, "2h-1h")
+	if _, err := NewFaultyDB(p, &failDB{}); err == nil {
+		t.Fatal("the window ending before its start should fail")
+	}
+}
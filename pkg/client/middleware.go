@@ -0,0 +1,304 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"runtime/trace"
+	"strings"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// Op describes an operation run through a MiddlewareDB.
+type Op struct {
+	// Name is the name of the operation, e.g. READ or BATCH_INSERT.
+	Name  string
+	Table string
+	// Keys are the keys of the operation, or the start key of a scan.
+	Keys []string
+	// Fields are the fields to read or scan, nil for all.
+	Fields []string
+	// Count is the number of the records to scan.
+	Count int
+	// Values are the values of every key to insert or update.
+	Values []map[string][]byte
+}
+
+// MiddlewareDB implements ycsb.DB, ycsb.BatchDB, ycsb.AnalyzeDB,
+// ycsb.TransactionalDB, ycsb.QueryDB and ycsb.ErrorClassifier by running
+// every operation of DB through Around. If DB is not a ycsb.BatchDB, a batch
+// runs as the single operations. Begin, Commit, Abort and CreateIndex are
+// passed to DB directly.
+type MiddlewareDB struct {
+	DB ycsb.DB
+	// Around runs the operation f, and returns its error.
+	Around func(ctx context.Context, op *Op, f func() error) error
+}
+
+// ClassifyError classifies the errors with the classifier of DB.
+func (db *MiddlewareDB) ClassifyError(err error) ycsb.ErrorClass {
+	return classifyError(db.DB, err)
+}
+
+func (db *MiddlewareDB) Close() error {
+	return db.DB.Close()
+}
+
+func (db *MiddlewareDB) InitThread(ctx context.Context, threadID int, threadCount int) context.Context {
+	return db.DB.InitThread(ctx, threadID, threadCount)
+}
+
+func (db *MiddlewareDB) CleanupThread(ctx context.Context) {
+	db.DB.CleanupThread(ctx)
+}
+
+func (db *MiddlewareDB) Read(ctx context.Context, table string, key string, fields []string) (res map[string][]byte, err error) {
+	err = db.Around(ctx, &Op{Name: "READ", Table: table, Keys: []string{key}, Fields: fields}, func() (err error) {
+		res, err = db.DB.Read(ctx, table, key, fields)
+		return err
+	})
+	return res, err
+}
+
+func (db *MiddlewareDB) BatchRead(ctx context.Context, table string, keys []string, fields []string) (res []map[string][]byte, err error) {
+	batchDB, ok := db.DB.(ycsb.BatchDB)
+	if !ok {
+		for _, key := range keys {
+			if _, err := db.Read(ctx, table, key, fields); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	err = db.Around(ctx, &Op{Name: "BATCH_READ", Table: table, Keys: keys, Fields: fields}, func() (err error) {
+		res, err = batchDB.BatchRead(ctx, table, keys, fields)
+		return err
+	})
+	return res, err
+}
+
+func (db *MiddlewareDB) Scan(ctx context.Context, table string, startKey string, count int, fields []string) (res []map[string][]byte, err error) {
+	err = db.Around(ctx, &Op{Name: "SCAN", Table: table, Keys: []string{startKey}, Fields: fields, Count: count}, func() (err error) {
+		res, err = db.DB.Scan(ctx, table, startKey, count, fields)
+		return err
+	})
+	return res, err
+}
+
+func (db *MiddlewareDB) Update(ctx context.Context, table string, key string, values map[string][]byte) error {
+	return db.Around(ctx, &Op{Name: "UPDATE", Table: table, Keys: []string{key}, Values: []map[string][]byte{values}}, func() error {
+		return db.DB.Update(ctx, table, key, values)
+	})
+}
+
+func (db *MiddlewareDB) BatchUpdate(ctx context.Context, table string, keys []string, values []map[string][]byte) error {
+	batchDB, ok := db.DB.(ycsb.BatchDB)
+	if !ok {
+		for i := range keys {
+			if err := db.Update(ctx, table, keys[i], values[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return db.Around(ctx, &Op{Name: "BATCH_UPDATE", Table: table, Keys: keys, Values: values}, func() error {
+		return batchDB.BatchUpdate(ctx, table, keys, values)
+	})
+}
+
+func (db *MiddlewareDB) Insert(ctx context.Context, table string, key string, values map[string][]byte) error {
+	return db.Around(ctx, &Op{Name: "INSERT", Table: table, Keys: []string{key}, Values: []map[string][]byte{values}}, func() error {
+		return db.DB.Insert(ctx, table, key, values)
+	})
+}
+
+func (db *MiddlewareDB) BatchInsert(ctx context.Context, table string, keys []string, values []map[string][]byte) error {
+	batchDB, ok := db.DB.(ycsb.BatchDB)
+	if !ok {
+		for i := range keys {
+			if err := db.Insert(ctx, table, keys[i], values[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return db.Around(ctx, &Op{Name: "BATCH_INSERT", Table: table, Keys: keys, Values: values}, func() error {
+		return batchDB.BatchInsert(ctx, table, keys, values)
+	})
+}
+
+func (db *MiddlewareDB) Delete(ctx context.Context, table string, key string) error {
+	return db.Around(ctx, &Op{Name: "DELETE", Table: table, Keys: []string{key}}, func() error {
+		return db.DB.Delete(ctx, table, key)
+	})
+}
+
+func (db *MiddlewareDB) BatchDelete(ctx context.Context, table string, keys []string) error {
+	batchDB, ok := db.DB.(ycsb.BatchDB)
+	if !ok {
+		for _, key := range keys {
+			if err := db.Delete(ctx, table, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return db.Around(ctx, &Op{Name: "BATCH_DELETE", Table: table, Keys: keys}, func() error {
+		return batchDB.BatchDelete(ctx, table, keys)
+	})
+}
+
+func (db *MiddlewareDB) Analyze(ctx context.Context, table string) error {
+	if analyzeDB, ok := db.DB.(ycsb.AnalyzeDB); ok {
+		return analyzeDB.Analyze(ctx, table)
+	}
+	return nil
+}
+
+func (db *MiddlewareDB) Begin(ctx context.Context) (context.Context, error) {
+	if txnDB, ok := db.DB.(ycsb.TransactionalDB); ok {
+		return txnDB.Begin(ctx)
+	}
+	return nil, ycsb.ErrNotTransactional
+}
+
+func (db *MiddlewareDB) Commit(ctx context.Context) error {
+	if txnDB, ok := db.DB.(ycsb.TransactionalDB); ok {
+		return txnDB.Commit(ctx)
+	}
+	return ycsb.ErrNotTransactional
+}
+
+func (db *MiddlewareDB) Abort(ctx context.Context) error {
+	if txnDB, ok := db.DB.(ycsb.TransactionalDB); ok {
+		return txnDB.Abort(ctx)
+	}
+	return ycsb.ErrNotTransactional
+}
+
+func (db *MiddlewareDB) CreateIndex(ctx context.Context, table string, field string) error {
+	if queryDB, ok := db.DB.(ycsb.QueryDB); ok {
+		return queryDB.CreateIndex(ctx, table, field)
+	}
+	return ycsb.ErrNotQueryable
+}
+
+func (db *MiddlewareDB) Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) (res []map[string][]byte, err error) {
+	queryDB, ok := db.DB.(ycsb.QueryDB)
+	if !ok {
+		return nil, ycsb.ErrNotQueryable
+	}
+
+	err = db.Around(ctx, &Op{Name: "QUERY", Table: table, Fields: fields, Count: count}, func() (err error) {
+		res, err = queryDB.Query(ctx, table, field, low, high, count, fields)
+		return err
+	})
+	return res, err
+}
+
+// logging prints every operation with its latency and error.
+func logging(ctx context.Context, op *Op, f func() error) error {
+	start := time.Now()
+	err := f()
+	lan := time.Now().Sub(start)
+	if err != nil {
+		fmt.Printf("%s %s %s takes %s, err: %v\n", op.Name, op.Table, strings.Join(op.Keys, ","), lan, err)
+	} else {
+		fmt.Printf("%s %s %s takes %s\n", op.Name, op.Table, strings.Join(op.Keys, ","), lan)
+	}
+	return err
+}
+
+// tracing marks every operation as a region of the runtime trace, which can
+// be collected from /debug/pprof/trace on the debug.pprof address and viewed
+// by go tool trace.
+func tracing(ctx context.Context, op *Op, f func() error) error {
+	defer trace.StartRegion(ctx, op.Name).End()
+	if trace.IsEnabled() {
+		trace.Log(ctx, "keys", strings.Join(op.Keys, ","))
+	}
+	return f()
+}
+
+type middlewareFunc func(ctx context.Context, op *Op, f func() error) error
+
+func (around middlewareFunc) Create(p *properties.Properties, db ycsb.DB) (ycsb.DB, error) {
+	return &MiddlewareDB{DB: db, Around: around}, nil
+}
+
+type measureCreator struct{}
+
+func (measureCreator) Create(p *properties.Properties, db ycsb.DB) (ycsb.DB, error) {
+	return DbWrapper{DB: db}, nil
+}
+
+type retryCreator struct{}
+
+func (retryCreator) Create(p *properties.Properties, db ycsb.DB) (ycsb.DB, error) {
+	return NewRetryDB(p, db)
+}
+
+type rateLimitCreator struct{}
+
+func (rateLimitCreator) Create(p *properties.Properties, db ycsb.DB) (ycsb.DB, error) {
+	ops := p.GetFloat64(prop.RateLimitOps, 0)
+	if ops <= 0 {
+		return nil, fmt.Errorf("%s must be positive, but got %v", prop.RateLimitOps, ops)
+	}
+	return &MiddlewareDB{DB: db, Around: newRateLimiter(ops).around}, nil
+}
+
+// WrapDB wraps the DB created by the DBCreator with the middlewares listed
+// in db.middleware, the first one is the outermost.
+func WrapDB(p *properties.Properties, db ycsb.DB) (ycsb.DB, error) {
+	defaultMiddleware := prop.DBMiddlewareDefault
+	if p.GetInt(prop.RetryMaxAttempts, prop.RetryMaxAttemptsDefault) > 1 {
+		defaultMiddleware += ",retry"
+	}
+	names := strings.Split(p.GetString(prop.DBMiddleware, defaultMiddleware), ",")
+
+	for i := len(names) - 1; i >= 0; i-- {
+		name := strings.TrimSpace(names[i])
+		if name == "" {
+			continue
+		}
+		creator := ycsb.GetDBMiddlewareCreator(name)
+		if creator == nil {
+			return nil, fmt.Errorf("middleware %s is not registered", name)
+		}
+
+		var err error
+		if db, err = creator.Create(p, db); err != nil {
+			return nil, fmt.Errorf("create middleware %s failed %v", name, err)
+		}
+	}
+	return db, nil
+}
+
+func init() {
+	ycsb.RegisterDBMiddlewareCreator("measure", measureCreator{})
+	ycsb.RegisterDBMiddlewareCreator("retry", retryCreator{})
+	ycsb.RegisterDBMiddlewareCreator("logging", middlewareFunc(logging))
+	ycsb.RegisterDBMiddlewareCreator("trace", middlewareFunc(tracing))
+	ycsb.RegisterDBMiddlewareCreator("ratelimit", rateLimitCreator{})
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+func TestWrapDB(t *testing.T) {
+	p := newTestProperties(t,
+		prop.DBMiddleware, "measure, trace, ratelimit",
+		prop.RateLimitOps, "100",
+	)
+	measurement.InitMeasure(p)
+
+	db, err := WrapDB(p, sleepDB{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.(ycsb.BatchDB); !ok {
+		t.Fatalf("%T must be a BatchDB", db)
+	}
+
+	// the batch runs as the single reads, which are limited to 100 ops/sec.
+	start := time.Now()
+	keys := make([]string, 11)
+	if _, err := db.(ycsb.BatchDB).BatchRead(context.Background(), "t", keys, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("11 reads at 100 ops/sec take at least 100ms, but take %s", elapsed)
+	}
+	if count := rowValue(t, outputRows(t, p), "BATCH_READ", "Count"); count != 1 {
+		t.Fatalf("want 1 batch read, but got %d", count)
+	}
+
+	p.Set(prop.DBMiddleware, "measure,unknown")
+	if _, err := WrapDB(p, sleepDB{}); err == nil {
+		t.Fatal("unknown middleware should fail")
+	}
+}
+
+// txnDB counts the transactions committed and aborted.
+type txnDB struct {
+	sleepDB
+	commits int
+	aborts  int
+}
+
+func (db *txnDB) Begin(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+func (db *txnDB) Commit(ctx context.Context) error {
+	db.commits++
+	return nil
+}
+
+func (db *txnDB) Abort(ctx context.Context) error {
+	db.aborts++
+	return nil
+}
+
+func TestTransactionalMiddleware(t *testing.T) {
+	p := newTestProperties(t,
+		prop.DBMiddleware, "measure,logging",
+	)
+	measurement.InitMeasure(p)
+
+	inner := new(txnDB)
+	db, err := WrapDB(p, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := db.(ycsb.TransactionalDB)
+	ctx, err := txn.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Abort(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if inner.commits != 1 || inner.aborts != 1 {
+		t.Fatalf("want 1 commit and 1 abort, but got %d and %d", inner.commits, inner.aborts)
+	}
+	if count := rowValue(t, outputRows(t, p), "COMMIT", "Count"); count != 1 {
+		t.Fatalf("want 1 measured commit, but got %d", count)
+	}
+
+	db, err = WrapDB(p, sleepDB{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.(ycsb.TransactionalDB).Begin(context.Background()); err != ycsb.ErrNotTransactional {
+		t.Fatalf("want %v, but got %v", ycsb.ErrNotTransactional, err)
+	}
+}
+
+// queryDB returns a row for every query.
+type queryDB struct {
+	sleepDB
+}
+
+func (db queryDB) CreateIndex(ctx context.Context, table string, field string) error {
+	return nil
+}
+
+func (db queryDB) Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) ([]map[string][]byte, error) {
+	return []map[string][]byte{{field: low}}, nil
+}
+
+func TestQueryMiddleware(t *testing.T) {
+	p := newTestProperties(t,
+		prop.DBMiddleware, "measure,logging",
+	)
+	measurement.InitMeasure(p)
+
+	db, err := WrapDB(p, queryDB{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.(ycsb.QueryDB).Query(context.Background(), "t", "field0", []byte("1"), []byte("1"), 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("want 1 row, but got %d", len(rows))
+	}
+	if count := rowValue(t, outputRows(t, p), "QUERY", "Count"); count != 1 {
+		t.Fatalf("want 1 measured query, but got %d", count)
+	}
+
+	db, err = WrapDB(p, sleepDB{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.(ycsb.QueryDB).CreateIndex(context.Background(), "t", "field0"); err != ycsb.ErrNotQueryable {
+		t.Fatalf("want %v, but got %v", ycsb.ErrNotQueryable, err)
+	}
+}
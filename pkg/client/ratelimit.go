@@ -0,0 +1,70 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the operations of all workers to a rate, a batch takes
+// as many slots as its keys. Unlike the target, it limits the operations on
+// the DB, e.g. including the retries of the middlewares outside it.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(ops float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / ops)}
+}
+
+// wait blocks until the next n slots.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(n) * l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (l *rateLimiter) around(ctx context.Context, op *Op, f func() error) error {
+	n := len(op.Keys)
+	if op.Name == "SCAN" || n == 0 {
+		n = 1
+	}
+	if err := l.wait(ctx, n); err != nil {
+		return err
+	}
+	return f()
+}
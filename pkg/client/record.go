@@ -0,0 +1,123 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// RecordDB records every operation issued to DB after the warm-up as a
+// ycsb.TraceOp to record.file, which the replay workload can issue again.
+type RecordDB struct {
+	MiddlewareDB
+
+	mu    sync.Mutex
+	start time.Time
+	f     *os.File
+	w     *bufio.Writer
+	enc   *json.Encoder
+	err   error
+}
+
+// NewRecordDB creates the RecordDB and its trace file.
+func NewRecordDB(p *properties.Properties, db ycsb.DB) (*RecordDB, error) {
+	name := p.GetString(prop.RecordFile, "")
+	if name == "" {
+		return nil, fmt.Errorf("%s is not set", prop.RecordFile)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RecordDB{
+		start: time.Now(),
+		f:     f,
+		w:     bufio.NewWriter(f),
+	}
+	r.enc = json.NewEncoder(r.w)
+	r.MiddlewareDB = MiddlewareDB{DB: db, Around: r.record}
+	return r, nil
+}
+
+func (db *RecordDB) record(ctx context.Context, op *Op, f func() error) error {
+	if !measurement.IsWarmUpFinished() {
+		return f()
+	}
+
+	t := &ycsb.TraceOp{
+		Op:     op.Name,
+		Table:  op.Table,
+		Keys:   op.Keys,
+		Fields: op.Fields,
+		Count:  op.Count,
+	}
+	if len(op.Values) > 0 {
+		t.Sizes = make([]map[string]int, len(op.Values))
+		for i, values := range op.Values {
+			sizes := make(map[string]int, len(values))
+			for field, value := range values {
+				sizes[field] = len(value)
+			}
+			t.Sizes[i] = sizes
+		}
+	}
+
+	db.mu.Lock()
+	t.Time = time.Now().Sub(db.start).Microseconds()
+	if db.err == nil {
+		db.err = db.enc.Encode(t)
+	}
+	db.mu.Unlock()
+	return f()
+}
+
+// Close writes the rest of the trace file and closes DB.
+func (db *RecordDB) Close() error {
+	db.mu.Lock()
+	err := db.err
+	if err == nil {
+		err = db.w.Flush()
+	}
+	if cerr := db.f.Close(); err == nil {
+		err = cerr
+	}
+	db.mu.Unlock()
+
+	if cerr := db.DB.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type recordCreator struct{}
+
+func (recordCreator) Create(p *properties.Properties, db ycsb.DB) (ycsb.DB, error) {
+	return NewRecordDB(p, db)
+}
+
+func init() {
+	ycsb.RegisterDBMiddlewareCreator("record", recordCreator{})
+}
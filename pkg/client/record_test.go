@@ -0,0 +1,97 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// nopDB is a DB whose operations succeed doing nothing.
+type nopDB struct {
+	ycsb.DB
+}
+
+func (nopDB) Close() error {
+	return nil
+}
+
+func (nopDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+func (nopDB) Insert(ctx context.Context, table string, key string, values map[string][]byte) error {
+	return nil
+}
+
+func TestRecordDB(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "trace.jsonl")
+	p := newTestProperties(t, prop.RecordFile, name)
+	db, err := NewRecordDB(p, nopDB{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	// the operations during warm-up are not recorded.
+	measurement.EnableWarmUp(true)
+	if _, err := db.Read(ctx, "t", "k0", nil); err != nil {
+		t.Fatal(err)
+	}
+	measurement.EnableWarmUp(false)
+	if _, err := db.Read(ctx, "t", "k1", []string{"field0"}); err != nil {
+		t.Fatal(err)
+	}
+	// the batch runs as the single inserts of nopDB.
+	values := []map[string][]byte{{"field0": make([]byte, 3)}, {"field0": make([]byte, 5)}}
+	if err := db.BatchInsert(ctx, "t", []string{"k2", "k3"}, values); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var ops []ycsb.TraceOp
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var op ycsb.TraceOp
+		if err := json.Unmarshal(s.Bytes(), &op); err != nil {
+			t.Fatal(err)
+		}
+		ops = append(ops, op)
+	}
+	if len(ops) != 3 {
+		t.Fatalf("want 3 operations, but got %v", ops)
+	}
+	if ops[0].Op != "READ" || ops[0].Keys[0] != "k1" || ops[0].Fields[0] != "field0" {
+		t.Fatalf("bad read %v", ops[0])
+	}
+	if ops[2].Op != "INSERT" || ops[2].Keys[0] != "k3" || ops[2].Sizes[0]["field0"] != 5 || ops[2].Time < ops[1].Time {
+		t.Fatalf("bad insert %v", ops[2])
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// RetryDB retries the failed operations of DB with an exponential backoff
+// if their errors are of a retryable class. Every attempt is measured as
+// <OP>_ATTEMPT and every retry as <OP>_RETRY with the backoff delay as the
+// latency, while the measure middleware around it measures the operations
+// end to end.
+type RetryDB struct {
+	MiddlewareDB
+
+	maxAttempts         int
+	initialInterval     time.Duration
+	maxInterval         time.Duration
+	multiplier          float64
+	randomizationFactor float64
+	classes             map[ycsb.ErrorClass]bool
+}
+
+// NewRetryDB creates the RetryDB with the retry properties.
+func NewRetryDB(p *properties.Properties, db ycsb.DB) (*RetryDB, error) {
+	r := &RetryDB{
+		maxAttempts:         p.GetInt(prop.RetryMaxAttempts, prop.RetryMaxAttemptsDefault),
+		initialInterval:     time.Duration(p.GetInt64(prop.RetryInitialInterval, prop.RetryInitialIntervalDefault)) * time.Millisecond,
+		maxInterval:         time.Duration(p.GetInt64(prop.RetryMaxInterval, prop.RetryMaxIntervalDefault)) * time.Millisecond,
+		multiplier:          p.GetFloat64(prop.RetryMultiplier, prop.RetryMultiplierDefault),
+		randomizationFactor: p.GetFloat64(prop.RetryJitter, prop.RetryJitterDefault),
+		classes:             make(map[ycsb.ErrorClass]bool),
+	}
+	if r.maxAttempts < 1 {
+		return nil, fmt.Errorf("%s must be at least 1, but got %d", prop.RetryMaxAttempts, r.maxAttempts)
+	}
+
+	for _, name := range strings.Split(p.GetString(prop.RetryClasses, prop.RetryClassesDefault), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		class, ok := parseErrorClass(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown error class %s in %s", name, prop.RetryClasses)
+		}
+		r.classes[class] = true
+	}
+	r.MiddlewareDB = MiddlewareDB{DB: db, Around: r.retry}
+	return r, nil
+}
+
+func parseErrorClass(name string) (ycsb.ErrorClass, bool) {
+	for class := ycsb.ErrorOther; class <= ycsb.ErrorThrottled; class++ {
+		if strings.EqualFold(class.String(), name) {
+			return class, true
+		}
+	}
+	return ycsb.ErrorOther, false
+}
+
+func (db *RetryDB) newBackOff(ctx context.Context) backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = db.initialInterval
+	b.MaxInterval = db.maxInterval
+	b.Multiplier = db.multiplier
+	b.RandomizationFactor = db.randomizationFactor
+	// the attempts limit the retries instead of the elapsed time.
+	b.MaxElapsedTime = 0
+	return backoff.WithContext(backoff.WithMaxRetries(b, uint64(db.maxAttempts-1)), ctx)
+}
+
+// txnKey marks the context of an open transaction.
+const txnKey = contextKey("txn")
+
+// Begin starts the transaction of the DB. The operations in it are not
+// retried, since a failed one may roll back the whole transaction on the
+// server, the error is returned for the caller to abort or retry the whole
+// transaction.
+func (db *RetryDB) Begin(ctx context.Context) (context.Context, error) {
+	txnCtx, err := db.MiddlewareDB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(txnCtx, txnKey, true), nil
+}
+
+// retry runs f until it succeeds, fails with an error which is not
+// retryable, or runs out of the attempts.
+func (db *RetryDB) retry(ctx context.Context, op *Op, f func() error) error {
+	if db.maxAttempts == 1 || ctx.Value(txnKey) != nil {
+		return f()
+	}
+
+	return backoff.RetryNotify(func() error {
+		start := time.Now()
+		err := f()
+		if err == nil || ctx.Err() == nil {
+			measurement.MeasureContext(ctx, op.Name+"_ATTEMPT", start, time.Now().Sub(start))
+		}
+		if err != nil && !db.classes[classifyError(db.DB, err)] {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, db.newBackOff(ctx), func(_ error, delay time.Duration) {
+		measurement.MeasureContext(ctx, op.Name+"_RETRY", time.Now(), delay)
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+func TestRetryDB(t *testing.T) {
+	p := newTestProperties(t,
+		prop.RetryMaxAttempts, "3",
+		prop.RetryInitialInterval, "1",
+	)
+	measurement.InitMeasure(p)
+
+	conflict := ycsb.WrapError(ycsb.ErrorConflict, errors.New("write conflict"))
+	inner := &failDB{errs: []error{
+		// the first read succeeds at the third attempt.
+		conflict, errBusy, nil,
+		// the second read runs out of the attempts.
+		conflict, conflict, conflict,
+		// the third read fails with an error which is not retryable.
+		errors.New("unknown"),
+	}}
+	db, err := WrapDB(p, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := db.Read(ctx, "t", "k", nil); err != nil {
+		t.Fatalf("read should succeed after the retries, but got %v", err)
+	}
+	if _, err := db.Read(ctx, "t", "k", nil); err != conflict {
+		t.Fatalf("read should fail with the last error, but got %v", err)
+	}
+	if _, err := db.Read(ctx, "t", "k", nil); ycsb.ClassifyError(err) != ycsb.ErrorOther {
+		t.Fatalf("read should fail without retries, but got %v", err)
+	}
+	if len(inner.errs) != 0 {
+		t.Fatalf("%d errors are not returned", len(inner.errs))
+	}
+
+	rows := outputRows(t, p)
+	for op, want := range map[string]int64{
+		"READ":                1,
+		"READ_ERROR":          2,
+		"READ_ERROR_CONFLICT": 1,
+		"READ_ERROR_OTHER":    1,
+		"READ_ATTEMPT":        7,
+		"READ_RETRY":          4,
+	} {
+		if count := rowValue(t, rows, op, "Count"); count != want {
+			t.Fatalf("want %d of %s, but got %d", want, op, count)
+		}
+	}
+}
+
+// txnFailDB is a transactional failDB.
+type txnFailDB struct {
+	*failDB
+}
+
+func (db txnFailDB) Begin(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+func (db txnFailDB) Commit(ctx context.Context) error {
+	return nil
+}
+
+func (db txnFailDB) Abort(ctx context.Context) error {
+	return nil
+}
+
+func TestRetryDBTransaction(t *testing.T) {
+	p := newTestProperties(t,
+		prop.RetryMaxAttempts, "3",
+		prop.RetryInitialInterval, "1",
+	)
+	measurement.InitMeasure(p)
+
+	conflict := ycsb.WrapError(ycsb.ErrorConflict, errors.New("deadlock"))
+	inner := txnFailDB{&failDB{errs: []error{conflict, nil}}}
+	db, err := WrapDB(p, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := db.(ycsb.TransactionalDB).Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read(ctx, "t", "k", nil); err != conflict {
+		t.Fatalf("read in the transaction should fail without retries, but got %v", err)
+	}
+	if len(inner.errs) != 1 {
+		t.Fatalf("want 1 error left, but got %d", len(inner.errs))
+	}
+
+	// the read outside the transaction is retried.
+	inner.errs = []error{conflict, nil}
+	if _, err := db.Read(context.Background(), "t", "k", nil); err != nil {
+		t.Fatalf("read should succeed after the retries, but got %v", err)
+	}
+}
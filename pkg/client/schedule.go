@@ -0,0 +1,152 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+// phase is a period of the run in which the target throughput changes
+// linearly from startRate to endRate ops/sec.
+type phase struct {
+	name      string
+	duration  time.Duration
+	startRate float64
+	endRate   float64
+}
+
+// ops returns the number of operations issued in the phase.
+func (p phase) ops() float64 {
+	return (p.startRate + p.endRate) / 2 * p.duration.Seconds()
+}
+
+// offset returns the time since the phase start when the n-th operation of
+// the phase should start.
+func (p phase) offset(n float64) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+
+	// the operations issued by t are startRate*t + (endRate-startRate)*t^2/(2*duration),
+	// solve it for t.
+	var t float64
+	a := (p.endRate - p.startRate) / (2 * p.duration.Seconds())
+	if p.duration == 0 || a == 0 {
+		t = n / p.startRate
+	} else {
+		d := p.startRate*p.startRate + 4*a*n
+		if d < 0 {
+			d = 0
+		}
+		t = (math.Sqrt(d) - p.startRate) / (2 * a)
+	}
+	return time.Duration(t * float64(time.Second))
+}
+
+// schedule is the target throughput of a run, a sequence of phases.
+// The duration of the last phase may be 0, which means it never ends.
+type schedule struct {
+	phases []phase
+}
+
+// newSchedule creates the schedule from the target.schedule property, or
+// from the constant target if it is not set. It returns nil if the
+// throughput is not limited.
+func newSchedule(p *properties.Properties) (*schedule, error) {
+	if s := p.GetString(prop.TargetSchedule, ""); s != "" {
+		return parseSchedule(s)
+	}
+
+	if v := p.GetInt64(prop.Target, 0); v > 0 {
+		return constantSchedule(float64(v)), nil
+	}
+
+	return nil, nil
+}
+
+// constantSchedule returns the schedule of a constant target throughput that
+// never ends.
+func constantSchedule(rate float64) *schedule {
+	return &schedule{phases: []phase{{startRate: rate, endRate: rate}}}
+}
+
+// parseSchedule parses phases in the format of `name:duration:rate,...`, the
+// rate is either a constant `ops` or a linear ramp `startOps-endOps`.
+func parseSchedule(s string) (*schedule, error) {
+	sch := new(schedule)
+	for _, item := range strings.Split(s, ",") {
+		seps := strings.Split(strings.TrimSpace(item), ":")
+		if len(seps) != 3 {
+			return nil, fmt.Errorf("bad phase `%s`, expected format `name:duration:rate`", item)
+		}
+
+		ph := phase{name: seps[0]}
+		var err error
+		if ph.duration, err = time.ParseDuration(seps[1]); err != nil || ph.duration <= 0 {
+			return nil, fmt.Errorf("bad duration of phase `%s`", item)
+		}
+
+		rates := strings.SplitN(seps[2], "-", 2)
+		if ph.startRate, err = strconv.ParseFloat(rates[0], 64); err != nil || ph.startRate < 0 {
+			return nil, fmt.Errorf("bad rate of phase `%s`", item)
+		}
+		ph.endRate = ph.startRate
+		if len(rates) == 2 {
+			if ph.endRate, err = strconv.ParseFloat(rates[1], 64); err != nil || ph.endRate < 0 {
+				return nil, fmt.Errorf("bad rate of phase `%s`", item)
+			}
+		}
+
+		sch.phases = append(sch.phases, ph)
+	}
+	return sch, nil
+}
+
+// offset returns the time since the schedule start when the n-th operation
+// should start. It returns false if the schedule finishes before that.
+func (s *schedule) offset(n float64) (time.Duration, bool) {
+	var start time.Duration
+	for _, ph := range s.phases {
+		if ph.duration == 0 {
+			return start + ph.offset(n), true
+		}
+		ops := ph.ops()
+		if n < ops {
+			return start + ph.offset(n), true
+		}
+		n -= ops
+		start += ph.duration
+	}
+	return 0, false
+}
+
+// constantRate returns the target throughput if it never changes.
+func (s *schedule) constantRate() (float64, bool) {
+	if len(s.phases) != 1 || s.phases[0].duration != 0 {
+		return 0, false
+	}
+	return s.phases[0].startRate, true
+}
+
+// initialRate returns the target throughput at the start of the schedule.
+func (s *schedule) initialRate() float64 {
+	return s.phases[0].startRate
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleOffset(t *testing.T) {
+	sch, err := parseSchedule("ramp:10s:0-100, hold:5s:100,down:10s:100-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		n    float64
+		want time.Duration
+	}{
+		{0, 0},
+		// the ramp issues 5*t^2 operations by t.
+		{20, 2 * time.Second},
+		{500, 10 * time.Second},
+		{600, 11 * time.Second},
+		{1000, 15 * time.Second},
+		// the ramp down issues 100*t - 5*t^2 operations by t.
+		{1375, 20 * time.Second},
+	}
+	for _, tt := range tests {
+		got, ok := sch.offset(tt.n)
+		if !ok {
+			t.Fatalf("operation %v should be in the schedule", tt.n)
+		}
+		if d := got - tt.want; d > time.Millisecond || d < -time.Millisecond {
+			t.Errorf("offset of operation %v: want %s, but got %s", tt.n, tt.want, got)
+		}
+	}
+
+	if _, ok := sch.offset(1500); ok {
+		t.Errorf("operation 1500 should be after the schedule")
+	}
+}
+
+func TestParseScheduleError(t *testing.T) {
+	for _, s := range []string{"ramp:10s", "ramp:x:10", "ramp:10s:-1", "ramp:10s:1-x", "ramp:0s:10"} {
+		if _, err := parseSchedule(s); err == nil {
+			t.Errorf("schedule %q should be invalid", s)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+)
+
+// A trial passes only if its throughput reaches this fraction of the target,
+// otherwise the DB is already saturated at the target.
+const searchThroughputFraction = 0.95
+
+// SearchTrial is the result of one trial run of the throughput search.
+type SearchTrial struct {
+	// Target is the target throughput of the trial in ops/sec, 0 means unlimited.
+	Target int64
+	// Throughput is the achieved throughput in ops/sec.
+	Throughput float64
+	// Latency is the latency in us at the searched percentile.
+	Latency int64
+	// Pass is whether the trial meets the latency SLO at the target throughput.
+	Pass bool
+}
+
+// Search runs repeated short trials and binary-searches the highest target
+// throughput whose latency at the percentile stays under the SLO, see the
+// search.* properties. It returns all the trials in the order they run, and
+// an error if the latency of the operation can't be measured. Every trial
+// resets the global measurement.
+func (c *Client) Search(ctx context.Context) ([]SearchTrial, error) {
+	op := c.p.GetString(prop.SearchOperation, prop.SearchOperationDefault)
+	percentile := c.p.GetFloat64(prop.SearchPercentile, prop.SearchPercentileDefault)
+	slo := c.p.GetInt64(prop.SearchSLO, 0)
+	if slo <= 0 {
+		util.Fatalf("%s must be set to the latency SLO in us", prop.SearchSLO)
+	}
+	precision := c.p.GetFloat64(prop.SearchPrecision, prop.SearchPrecisionDefault)
+	maxTrials := c.p.GetInt(prop.SearchMaxTrials, prop.SearchMaxTrialsDefault)
+
+	// every trial is bounded by time instead of the operation count.
+	c.p.Set(prop.MaxExecutiontime, strconv.FormatInt(c.p.GetInt64(prop.SearchTrialTime, prop.SearchTrialTimeDefault), 10))
+	c.p.Set(prop.OperationCount, "0")
+	c.p.Delete(prop.TargetSchedule)
+
+	var trials []SearchTrial
+	trial := func(target int64) (SearchTrial, error) {
+		fmt.Printf("***************** search trial %d, target %d ops/sec *****************\n", len(trials)+1, target)
+		measurement.InitMeasure(c.p)
+		c.p.Set(prop.Target, strconv.FormatInt(target, 10))
+
+		var (
+			latency int64
+			qps     float64
+			ok      bool
+		)
+		// the throughput is taken when the workers end, before the validation.
+		err := c.run(ctx, func() {
+			latency, qps, ok = measurement.Percentile(op, percentile)
+		})
+		if err != nil {
+			return SearchTrial{}, err
+		}
+		if !ok {
+			if ctx.Err() != nil {
+				return SearchTrial{}, ctx.Err()
+			}
+			return SearchTrial{}, fmt.Errorf("no %s latency is measured, %s must name a measured operation and %s must be histogram",
+				op, prop.SearchOperation, prop.MeasurementType)
+		}
+
+		t := SearchTrial{
+			Target:     target,
+			Throughput: qps,
+			Latency:    latency,
+			Pass:       latency <= slo && (target == 0 || qps >= float64(target)*searchThroughputFraction),
+		}
+		fmt.Printf("target %d ops/sec, throughput %.1f ops/sec, %s p%v latency %dus, pass %v\n",
+			t.Target, t.Throughput, op, percentile, t.Latency, t.Pass)
+		trials = append(trials, t)
+		return t, nil
+	}
+
+	// lo is the highest passing target and hi the lowest failing one.
+	lo := c.p.GetInt64(prop.SearchMinTarget, 0)
+	hi := c.p.GetInt64(prop.SearchMaxTarget, 0)
+	if hi <= 0 {
+		// without an upper bound, the unlimited throughput is the one.
+		t, err := trial(0)
+		if err != nil || t.Pass || ctx.Err() != nil {
+			return trials, err
+		}
+		hi = int64(t.Throughput)
+	}
+
+	for len(trials) < maxTrials && ctx.Err() == nil && float64(hi-lo) > precision*float64(hi) {
+		mid := (lo + hi) / 2
+		t, err := trial(mid)
+		if err != nil {
+			return trials, err
+		}
+		if t.Pass {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return trials, nil
+}
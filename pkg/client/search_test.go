@@ -0,0 +1,72 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+func TestSearch(t *testing.T) {
+	// one thread reading for 5ms each can't do more than 200 ops/sec.
+	p := newTestProperties(t,
+		prop.ThreadCount, "1",
+		prop.SearchSLO, "1000000",
+		prop.SearchTrialTime, "1",
+		prop.SearchMaxTarget, "600",
+		prop.SearchMaxTrials, "3",
+	)
+
+	c := NewClient(p, readWorkload{}, DbWrapper{sleepDB{delay: 5 * time.Millisecond}})
+	trials, err := c.Search(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 300 is over the capacity, 150 under it, then 225 over it again.
+	want := []struct {
+		target int64
+		pass   bool
+	}{{300, false}, {150, true}, {225, false}}
+	if len(trials) != len(want) {
+		t.Fatalf("want %d trials, but got %v", len(want), trials)
+	}
+	for i, w := range want {
+		if trials[i].Target != w.target || trials[i].Pass != w.pass {
+			t.Fatalf("want trial %d of target %d to pass %v, but got %+v", i+1, w.target, w.pass, trials[i])
+		}
+	}
+}
+
+func TestSearchMissingOperation(t *testing.T) {
+	p := newTestProperties(t,
+		prop.ThreadCount, "1",
+		prop.SearchSLO, "1000000",
+		prop.SearchTrialTime, "1",
+		prop.SearchMaxTarget, "100",
+		prop.SearchOperation, "UPDATE",
+	)
+
+	c := NewClient(p, readWorkload{}, DbWrapper{sleepDB{delay: time.Millisecond}})
+	trials, err := c.Search(context.Background())
+	if err == nil {
+		t.Fatalf("the operation never measured should fail the search, but got %v", trials)
+	}
+	if len(trials) != 0 {
+		t.Fatalf("the search should stop at the first trial, but got %v", trials)
+	}
+}
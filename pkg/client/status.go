@@ -0,0 +1,254 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+)
+
+// StatusPath is the path prefix of the status API.
+const StatusPath = "/status"
+
+// States of a client reported by the status API.
+const (
+	stateIdle    = "idle"
+	stateRunning = "running"
+	statePaused  = "paused"
+)
+
+var errNotRunning = errors.New("no run is in progress")
+
+// runState is an immutable snapshot of the controls of a run, every change
+// through the status API stores a new one, so the workers only need an
+// atomic load to follow it.
+type runState struct {
+	// resumed is closed when the paused run is resumed, nil if not paused.
+	resumed chan struct{}
+	// pausedFor is the total time the run has been paused, the workers delay
+	// their schedule by it.
+	pausedFor   time.Duration
+	pausedSince time.Time
+	// schedule is the target throughput, nil means unlimited.
+	schedule *schedule
+}
+
+// paused returns the total time the run has been paused until now.
+func (st *runState) paused(now time.Time) time.Duration {
+	if st.resumed == nil {
+		return st.pausedFor
+	}
+	return st.pausedFor + now.Sub(st.pausedSince)
+}
+
+// control is the state of the client changed through the status API.
+type control struct {
+	mu      sync.Mutex
+	state   atomic.Value // *runState
+	running bool
+	// stop stops the workers of the current run gracefully.
+	stop context.CancelFunc
+}
+
+func newControl() *control {
+	c := new(control)
+	c.state.Store(new(runState))
+	return c
+}
+
+func (c *control) load() *runState {
+	return c.state.Load().(*runState)
+}
+
+func (c *control) start(sch *schedule, stop context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.Store(&runState{schedule: sch})
+	c.running = true
+	c.stop = stop
+}
+
+func (c *control) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if st := c.load(); st.resumed != nil {
+		close(st.resumed)
+	}
+	c.state.Store(new(runState))
+	c.running = false
+	c.stop = nil
+}
+
+func (c *control) pause() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.running {
+		return errNotRunning
+	}
+	st := *c.load()
+	if st.resumed == nil {
+		st.resumed = make(chan struct{})
+		st.pausedSince = time.Now()
+		c.state.Store(&st)
+	}
+	return nil
+}
+
+func (c *control) resume() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.running {
+		return errNotRunning
+	}
+	st := *c.load()
+	if st.resumed != nil {
+		close(st.resumed)
+		st.resumed = nil
+		st.pausedFor += time.Now().Sub(st.pausedSince)
+		c.state.Store(&st)
+	}
+	return nil
+}
+
+// setTarget replaces the schedule with a constant target throughput, 0 means unlimited.
+func (c *control) setTarget(target float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.running {
+		return errNotRunning
+	}
+	st := *c.load()
+	st.schedule = nil
+	if target > 0 {
+		st.schedule = constantSchedule(target)
+	}
+	c.state.Store(&st)
+	return nil
+}
+
+func (c *control) stopRun() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.running {
+		return errNotRunning
+	}
+	c.stop()
+	return nil
+}
+
+// status is the response of the status API.
+type status struct {
+	State string `json:"state"`
+	// Target is the constant target throughput in ops/sec, 0 means unlimited.
+	// It is omitted while a multi-phase schedule drives the run.
+	Target     *float64                          `json:"target,omitempty"`
+	Operations map[string]map[string]interface{} `json:"operations,omitempty"`
+}
+
+func (c *control) status() status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st := c.load()
+	s := status{State: stateIdle}
+	if !c.running {
+		return s
+	}
+
+	s.State = stateRunning
+	if st.resumed != nil {
+		s.State = statePaused
+	}
+	target := float64(0)
+	if st.schedule != nil {
+		var ok bool
+		if target, ok = st.schedule.constantRate(); ok {
+			s.Target = &target
+		}
+	} else {
+		s.Target = &target
+	}
+	s.Operations, _ = measurement.Stats()
+	return s
+}
+
+// StatusHandler returns the HTTP handler of the status API, which is served
+// under StatusPath:
+//
+//	GET  /status              the state and the live stats of every operation
+//	POST /status/pause        pause the workers
+//	POST /status/resume       resume the paused workers
+//	POST /status/target?ops=N set the target throughput, 0 means unlimited
+//	POST /status/stop         stop the run gracefully
+func (c *Client) StatusHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(StatusPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.control.status())
+	})
+
+	action := func(path string, fn func(r *http.Request) error) {
+		mux.HandleFunc(StatusPath+path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if err := fn(r); err != nil {
+				code := http.StatusBadRequest
+				if err == errNotRunning {
+					code = http.StatusConflict
+				}
+				http.Error(w, err.Error(), code)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(c.control.status())
+		})
+	}
+	action("/pause", func(*http.Request) error {
+		return c.control.pause()
+	})
+	action("/resume", func(*http.Request) error {
+		return c.control.resume()
+	})
+	action("/target", func(r *http.Request) error {
+		target, err := strconv.ParseFloat(r.FormValue("ops"), 64)
+		if err != nil || target < 0 {
+			return errors.New("ops must be the target throughput in ops/sec")
+		}
+		return c.control.setTarget(target)
+	})
+	action("/stop", func(*http.Request) error {
+		return c.control.stopRun()
+	})
+	return mux
+}
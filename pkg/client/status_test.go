@@ -0,0 +1,153 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+func getStatus(t *testing.T, method string, url string) status {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: %s", method, url, resp.Status)
+	}
+
+	var s status
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func readCount(s status) int64 {
+	if op, ok := s.Operations["READ"]; ok {
+		return int64(op[measurement.COUNT].(float64))
+	}
+	return 0
+}
+
+func TestStatusAPI(t *testing.T) {
+	p := newTestProperties(t,
+		prop.ThreadCount, "2",
+		prop.OperationCount, "0",
+		prop.Target, "200",
+	)
+	measurement.InitMeasure(p)
+
+	c := NewClient(p, readWorkload{}, DbWrapper{sleepDB{delay: time.Millisecond}})
+	srv := httptest.NewServer(c.StatusHandler())
+	defer srv.Close()
+
+	if s := getStatus(t, http.MethodGet, srv.URL+StatusPath); s.State != stateIdle {
+		t.Fatalf("want state %s before the run, but got %s", stateIdle, s.State)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(context.Background())
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	s := getStatus(t, http.MethodGet, srv.URL+StatusPath)
+	if s.State != stateRunning || s.Target == nil || *s.Target != 200 {
+		t.Fatalf("want running at target 200, but got %+v", s)
+	}
+
+	s = getStatus(t, http.MethodPost, srv.URL+StatusPath+"/pause")
+	if s.State != statePaused {
+		t.Fatalf("want state %s, but got %s", statePaused, s.State)
+	}
+	time.Sleep(50 * time.Millisecond)
+	paused := readCount(getStatus(t, http.MethodGet, srv.URL+StatusPath))
+	time.Sleep(200 * time.Millisecond)
+	if n := readCount(getStatus(t, http.MethodGet, srv.URL+StatusPath)); n != paused {
+		t.Fatalf("%d reads are done while paused", n-paused)
+	}
+
+	getStatus(t, http.MethodPost, srv.URL+StatusPath+"/resume")
+	s = getStatus(t, http.MethodPost, srv.URL+StatusPath+"/target?ops=0")
+	if s.State != stateRunning || s.Target == nil || *s.Target != 0 {
+		t.Fatalf("want running unlimited, but got %+v", s)
+	}
+	time.Sleep(200 * time.Millisecond)
+	// 2 threads unlimited take far more than 200 ops/sec.
+	if n := readCount(getStatus(t, http.MethodGet, srv.URL+StatusPath)); n-paused < 100 {
+		t.Fatalf("only %d reads are done after the target is unlimited", n-paused)
+	}
+
+	getStatus(t, http.MethodPost, srv.URL+StatusPath+"/stop")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run is not stopped")
+	}
+	if s := getStatus(t, http.MethodGet, srv.URL+StatusPath); s.State != stateIdle {
+		t.Fatalf("want state %s after the run, but got %s", stateIdle, s.State)
+	}
+}
+
+func TestPauseMaxExecutionTime(t *testing.T) {
+	p := newTestProperties(t,
+		prop.OperationCount, "0",
+		prop.MaxExecutiontime, "1",
+		prop.Target, "100",
+	)
+	measurement.InitMeasure(p)
+
+	c := NewClient(p, readWorkload{}, DbWrapper{sleepDB{delay: time.Millisecond}})
+	srv := httptest.NewServer(c.StatusHandler())
+	defer srv.Close()
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(context.Background())
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	getStatus(t, http.MethodPost, srv.URL+StatusPath+"/pause")
+	time.Sleep(1200 * time.Millisecond)
+	if s := getStatus(t, http.MethodGet, srv.URL+StatusPath); s.State != statePaused {
+		t.Fatalf("the paused run should not time out, but got state %s", s.State)
+	}
+
+	getStatus(t, http.MethodPost, srv.URL+StatusPath+"/resume")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run is not stopped")
+	}
+	// the run takes 1s besides the pause of 1.2s.
+	if d := time.Since(start); d < 2200*time.Millisecond {
+		t.Fatalf("want the run to take 2.2s at least, but it takes %s", d)
+	}
+}
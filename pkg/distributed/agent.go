@@ -0,0 +1,290 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/client"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+const (
+	preparePath = "/prepare"
+	startPath   = "/start"
+	abortPath   = "/abort"
+)
+
+// prepareTimeout is how long a prepared agent waits for the start, it drops
+// the run afterwards, e.g. if the coordinator is gone.
+const prepareTimeout = time.Minute
+
+// prepareRequest asks an agent to create the workload and the DB of a run.
+type prepareRequest struct {
+	DB         string            `json:"db"`
+	Properties map[string]string `json:"properties"`
+}
+
+// startRequest asks a prepared agent to start the run at StartAt, so that all
+// the agents start together.
+type startRequest struct {
+	StartAt time.Time `json:"start_at"`
+}
+
+// report is streamed by an agent during the run, it carries the histograms
+// measured since the previous report. The last report of a run is final.
+type report struct {
+	Final      bool                                     `json:"final"`
+	Error      string                                   `json:"error,omitempty"`
+	Histograms map[string]measurement.HistogramSnapshot `json:"histograms,omitempty"`
+}
+
+// Agent runs the benchmark driven by a coordinator, one run at a time.
+type Agent struct {
+	mu sync.Mutex
+
+	p        *properties.Properties
+	workload ycsb.Workload
+	db       ycsb.DB
+	// expire drops the prepared run if it doesn't start in time.
+	expire *time.Timer
+	// cancel stops the started run, nil if the run is not started.
+	cancel context.CancelFunc
+	// done is closed when the prepared run is cleaned up.
+	done chan struct{}
+}
+
+// NewAgent creates an agent.
+func NewAgent() *Agent {
+	return new(Agent)
+}
+
+// Handler returns the HTTP handler serving the coordinator.
+func (a *Agent) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(preparePath, a.handlePrepare)
+	mux.HandleFunc(startPath, a.handleStart)
+	mux.HandleFunc(abortPath, a.handleAbort)
+	return mux
+}
+
+func (a *Agent) handlePrepare(w http.ResponseWriter, r *http.Request) {
+	var req prepareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.p != nil {
+		http.Error(w, "agent is already prepared", http.StatusConflict)
+		return
+	}
+
+	if err := a.prepare(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Agent) prepare(req *prepareRequest) error {
+	p := properties.LoadMap(req.Properties)
+	measurement.InitMeasure(p)
+	// the histograms are sent back to be merged by the coordinator.
+	if _, err := measurement.Snapshot(); err != nil {
+		return err
+	}
+
+	workloadName := p.GetString(prop.Workload, "core")
+	workloadCreator := ycsb.GetWorkloadCreator(workloadName)
+	if workloadCreator == nil {
+		return fmt.Errorf("workload %s is not registered", workloadName)
+	}
+	workload, err := workloadCreator.Create(p)
+	if err != nil {
+		return fmt.Errorf("create workload %s failed %v", workloadName, err)
+	}
+
+	dbCreator := ycsb.GetDBCreator(req.DB)
+	if dbCreator == nil {
+		workload.Close()
+		return fmt.Errorf("%s is not registered", req.DB)
+	}
+	db, err := dbCreator.Create(p)
+	if err != nil {
+		workload.Close()
+		return fmt.Errorf("create db %s failed %v", req.DB, err)
+	}
+
+	if db, err = client.WrapDB(p, db); err != nil {
+		workload.Close()
+		return fmt.Errorf("wrap db %s failed %v", req.DB, err)
+	}
+
+	a.p = p
+	a.workload = workload
+	a.db = db
+	a.done = make(chan struct{})
+	a.expire = time.AfterFunc(prepareTimeout, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.p == p && a.cancel == nil {
+			a.cleanup()
+		}
+	})
+	return nil
+}
+
+func (a *Agent) handleStart(w http.ResponseWriter, r *http.Request) {
+	var req startRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	if a.p == nil {
+		a.mu.Unlock()
+		http.Error(w, "agent is not prepared", http.StatusConflict)
+		return
+	}
+	if a.cancel != nil {
+		a.mu.Unlock()
+		http.Error(w, "agent is already started", http.StatusConflict)
+		return
+	}
+	a.expire.Stop()
+	ctx, cancel := context.WithCancel(r.Context())
+	a.cancel = cancel
+	p, workload, db := a.p, a.workload, a.db
+	// the run holds no lock, so that it can be aborted.
+	a.mu.Unlock()
+
+	// release cleans up the run unless another one is prepared after it.
+	release := func() {
+		cancel()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.p == p {
+			a.cleanup()
+		}
+	}
+	defer release()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(time.Until(req.StartAt)):
+	}
+
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	// runErr is set by the run, it is read only after the run is done.
+	var runErr error
+	snapshot := func(final bool) *report {
+		rep := &report{Final: final}
+		var err error
+		if rep.Histograms, err = measurement.IntervalSnapshot(); err != nil {
+			rep.Error = err.Error()
+		} else if final && runErr != nil {
+			rep.Error = runErr.Error()
+		}
+		return rep
+	}
+	send := func(rep *report) bool {
+		if err := enc.Encode(rep); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return rep.Error == ""
+	}
+
+	c := client.NewClient(p, workload, db)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runErr = c.Run(ctx)
+	}()
+
+	t := time.NewTicker(time.Duration(p.GetInt64(prop.LogInterval, 10)) * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if !send(snapshot(false)) {
+				// the coordinator is gone, which cancels the run.
+				<-done
+				return
+			}
+		case <-done:
+			// the agent keeps its own share of the output, e.g. the
+			// HdrHistogram interval logs.
+			measurement.Output()
+			// the agent is released before the final report, after which
+			// the coordinator may prepare the next run.
+			rep := snapshot(true)
+			release()
+			send(rep)
+			return
+		}
+	}
+}
+
+// handleAbort drops the prepared run, or stops the started one, and returns
+// once the agent can be prepared again.
+func (a *Agent) handleAbort(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	if a.p == nil {
+		a.mu.Unlock()
+		return
+	}
+	done := a.done
+	if a.cancel != nil {
+		a.cancel()
+	} else {
+		a.expire.Stop()
+		a.cleanup()
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-r.Context().Done():
+	case <-done:
+	}
+}
+
+// cleanup closes the prepared run, a.mu must be held.
+func (a *Agent) cleanup() {
+	a.db.Close()
+	a.workload.Close()
+	close(a.done)
+	a.p = nil
+	a.workload = nil
+	a.db = nil
+	a.expire = nil
+	a.cancel = nil
+	a.done = nil
+}
@@ -0,0 +1,284 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+// startDelay is how long after being prepared the agents start the run
+// together, it must cover the time to send the start requests.
+const startDelay = time.Second
+
+// abortTimeout bounds how long the coordinator waits for the agents to abort.
+const abortTimeout = 10 * time.Second
+
+// Coordinator drives one benchmark from many agents.
+type Coordinator struct {
+	p      *properties.Properties
+	dbName string
+	agents []string
+	// mergeProps are the properties of the merged measurement, which
+	// doesn't write the interval logs of the agents again.
+	mergeProps *properties.Properties
+}
+
+// NewCoordinator creates a coordinator of the agents listening on the addresses.
+func NewCoordinator(p *properties.Properties, dbName string, agents []string) *Coordinator {
+	mergeProps := properties.LoadMap(p.Map())
+	mergeProps.Set(prop.HdrHistogramFileOutput, "false")
+	return &Coordinator{
+		p:          p,
+		dbName:     dbName,
+		agents:     agents,
+		mergeProps: mergeProps,
+	}
+}
+
+// split returns the share of total for the i-th of n agents.
+func split(total int64, i int, n int) (start int64, count int64) {
+	count = total / int64(n)
+	start = count * int64(i)
+	if rem := total % int64(n); int64(i) < rem {
+		count++
+		start += int64(i)
+	} else {
+		start += rem
+	}
+	return start, count
+}
+
+// unboundedInsertSpan is the number of the keys every agent may insert in
+// a run without operationcount.
+const unboundedInsertSpan = int64(1) << 32
+
+// agentProperties returns the properties of the i-th agent, which runs its
+// share of the operations and the target throughput, and inserts its share
+// of the keys. Every share of the operations or the loaded records must be
+// at least threadcount.
+func (c *Coordinator) agentProperties(i int) (map[string]string, error) {
+	n := len(c.agents)
+	m := c.p.Map()
+	set := func(key string, v int64) {
+		m[key] = strconv.FormatInt(v, 10)
+	}
+	threadCount := c.p.GetInt64(prop.ThreadCount, 1)
+	checkShare := func(name string, total int64, share int64) error {
+		if share < threadCount {
+			return fmt.Errorf("%s %d is split into %d for agent %d, which is less than %s %d",
+				name, total, share, i, prop.ThreadCount, threadCount)
+		}
+		return nil
+	}
+
+	operationCount := c.p.GetInt64(prop.OperationCount, 0)
+	opStart, opCount := split(operationCount, i, n)
+	if operationCount > 0 {
+		set(prop.OperationCount, opCount)
+	}
+	if v := c.p.GetInt64(prop.Target, 0); v > 0 {
+		_, target := split(v, i, n)
+		set(prop.Target, target)
+	}
+
+	recordCount := c.p.GetInt64(prop.RecordCount, prop.RecordCountDefault)
+	if c.p.GetBool(prop.DoTransactions, true) {
+		if operationCount > 0 {
+			if err := checkShare(prop.OperationCount, operationCount, opCount); err != nil {
+				return nil, err
+			}
+		} else {
+			opStart = int64(i) * unboundedInsertSpan
+		}
+		// the inserts of the run don't overlap.
+		set(prop.TransactionInsertStart, c.p.GetInt64(prop.TransactionInsertStart, recordCount)+opStart)
+		return m, nil
+	}
+
+	insertStart := c.p.GetInt64(prop.InsertStart, prop.InsertStartDefault)
+	insertCount := c.p.GetInt64(prop.InsertCount, recordCount-insertStart)
+	start, count := split(insertCount, i, n)
+	if err := checkShare(prop.InsertCount, insertCount, count); err != nil {
+		return nil, err
+	}
+	set(prop.InsertStart, insertStart+start)
+	set(prop.InsertCount, count)
+	return m, nil
+}
+
+func (c *Coordinator) post(ctx context.Context, agent string, path string, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+agent+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("agent %s: %s", agent, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// forEachAgent calls fn for all agents concurrently and returns the first error.
+func (c *Coordinator) forEachAgent(fn func(i int, agent string) error) error {
+	errs := make([]error, len(c.agents))
+	var wg sync.WaitGroup
+	wg.Add(len(c.agents))
+	for i, agent := range c.agents {
+		go func(i int, agent string) {
+			defer wg.Done()
+			errs[i] = fn(i, agent)
+		}(i, agent)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// abort drops the runs of all the agents, so that a failed run doesn't keep
+// any agent prepared. The errors are ignored, an agent which can't be
+// reached drops the run after prepareTimeout itself.
+func (c *Coordinator) abort() {
+	ctx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+	defer cancel()
+
+	c.forEachAgent(func(i int, agent string) error {
+		resp, err := c.post(ctx, agent, abortPath, struct{}{})
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+}
+
+// Run prepares all the agents, starts them together and blocks until they
+// all finish. The interval measurements of the agents are merged into the
+// global measurement, which is summarized every measurement.interval.
+func (c *Coordinator) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	props := make([]map[string]string, len(c.agents))
+	for i := range c.agents {
+		m, err := c.agentProperties(i)
+		if err != nil {
+			return err
+		}
+		props[i] = m
+	}
+
+	err := c.forEachAgent(func(i int, agent string) error {
+		resp, err := c.post(ctx, agent, preparePath, &prepareRequest{DB: c.dbName, Properties: props[i]})
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	if err != nil {
+		c.abort()
+		return err
+	}
+
+	measurement.InitMeasure(c.mergeProps)
+	// the agents have measured the operations after the warm-up already.
+	measurement.EnableWarmUp(false)
+
+	done := make(chan error, 1)
+	startAt := time.Now().Add(startDelay)
+	go func() {
+		done <- c.forEachAgent(func(i int, agent string) error {
+			err := c.stream(ctx, agent, startAt)
+			if err != nil {
+				// stop the other agents
+				cancel()
+			}
+			return err
+		})
+	}()
+
+	t := time.NewTicker(time.Duration(c.p.GetInt64(prop.LogInterval, 10)) * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			measurement.Summary()
+		case err := <-done:
+			if err != nil {
+				c.abort()
+			}
+			return err
+		}
+	}
+}
+
+// stream starts the agent and merges its reports until the final one.
+func (c *Coordinator) stream(ctx context.Context, agent string, startAt time.Time) error {
+	resp, err := c.post(ctx, agent, startPath, &startRequest{StartAt: startAt})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return fmt.Errorf("agent %s stops without the final report: %v", agent, err)
+		}
+
+		rep := new(report)
+		if err := json.Unmarshal(line, rep); err != nil {
+			return fmt.Errorf("agent %s: bad report: %v", agent, err)
+		}
+		// the final report of a failed validation has the histograms too.
+		if err := measurement.Merge(rep.Histograms); err != nil {
+			return err
+		}
+		if rep.Error != "" {
+			return fmt.Errorf("agent %s: %s", agent, rep.Error)
+		}
+		if rep.Final {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+
+	_ "github.com/pingcap/go-ycsb/db/basic"
+	_ "github.com/pingcap/go-ycsb/pkg/workload"
+)
+
+// agentEnv makes the test binary run as an agent, because the measurement is
+// global and every agent needs its own process.
+const agentEnv = "GO_YCSB_TEST_AGENT"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(agentEnv) != "" {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(l.Addr().String())
+		http.Serve(l, NewAgent().Handler())
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// startAgent starts an agent process and returns its address.
+func startAgent(t *testing.T) string {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), agentEnv+"=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	addr, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr[:len(addr)-1]
+}
+
+func TestSplit(t *testing.T) {
+	var next int64
+	for i := 0; i < 3; i++ {
+		start, count := split(10, i, 3)
+		if start != next {
+			t.Fatalf("agent %d should start at %d, but got %d", i, next, start)
+		}
+		next += count
+	}
+	if next != 10 {
+		t.Fatalf("want 10 in total, but got %d", next)
+	}
+}
+
+func TestAgentProperties(t *testing.T) {
+	p := properties.NewProperties()
+	p.Set(prop.RecordCount, "100")
+	p.Set(prop.OperationCount, "30")
+	p.Set(prop.ThreadCount, "10")
+	c := NewCoordinator(p, "basic", []string{"a", "b", "c"})
+
+	// the inserts of the run start after the keys of the previous agents.
+	for i, want := range []string{"100", "110", "120"} {
+		m, err := c.agentProperties(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m[prop.OperationCount] != "10" || m[prop.TransactionInsertStart] != want {
+			t.Fatalf("want 10 operations from %s for agent %d, but got %s from %s",
+				want, i, m[prop.OperationCount], m[prop.TransactionInsertStart])
+		}
+	}
+
+	// the shares of fewer operations than threadcount are rejected.
+	p.Set(prop.OperationCount, "20")
+	if _, err := c.agentProperties(2); err == nil {
+		t.Fatal("the share of 6 operations should be rejected for 10 threads")
+	}
+	p.Set(prop.OperationCount, "2")
+	if err := c.Run(context.Background()); err == nil {
+		t.Fatal("the share of 0 operations should fail the run")
+	}
+
+	p.Set(prop.DoTransactions, "false")
+	p.Set(prop.InsertCount, "20")
+	if _, err := c.agentProperties(0); err == nil {
+		t.Fatal("the share of 6 records should be rejected for 10 threads")
+	}
+}
+
+func TestCoordinator(t *testing.T) {
+	agents := []string{startAgent(t), startAgent(t), startAgent(t)}
+
+	for _, doTransactions := range []string{"false", "true"} {
+		p := properties.NewProperties()
+		p.Set(prop.DoTransactions, doTransactions)
+		p.Set(prop.RecordCount, "1000")
+		p.Set(prop.OperationCount, "1000")
+		p.Set(prop.ThreadCount, "4")
+		p.Set(prop.ReadProportion, "1")
+		p.Set(prop.UpdateProportion, "0")
+		p.Set(prop.LogInterval, "1")
+
+		c := NewCoordinator(p, "basic", agents)
+		if err := c.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		op := "INSERT"
+		if doTransactions == "true" {
+			op = "READ"
+		}
+		_, qps, ok := measurement.Percentile(op, 99)
+		if !ok || qps <= 0 {
+			t.Fatalf("%s is not measured", op)
+		}
+		stats, err := measurement.Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count := stats[op][measurement.COUNT]; count != int64(1000) {
+			t.Fatalf("want 1000 %s merged, but got %v", op, count)
+		}
+	}
+}
+
+func TestCoordinatorAbort(t *testing.T) {
+	agents := []string{startAgent(t), startAgent(t)}
+
+	p := properties.NewProperties()
+	p.Set(prop.RecordCount, "100")
+	p.Set(prop.OperationCount, "100")
+	p.Set(prop.LogInterval, "1")
+
+	// the prepared agents are aborted if any agent fails.
+	c := NewCoordinator(p, "basic", append(agents, "127.0.0.1:1"))
+	if err := c.Run(context.Background()); err == nil {
+		t.Fatal("the unreachable agent should fail the run")
+	}
+	c = NewCoordinator(p, "basic", agents)
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// Trace types.
+const (
+	// TraceFrequency samples the keys randomly by their frequencies.
+	TraceFrequency = "frequency"
+	// TraceSequence generates the keys in the order of the trace.
+	TraceSequence = "sequence"
+)
+
+// Trace generates the integers of the keys in a trace, either randomly by
+// their frequencies or in the order of the trace.
+type Trace struct {
+	Number
+	nums []int64
+	// cumWeights are the cumulative frequencies of nums, nil if the keys
+	// are generated in order.
+	cumWeights []int64
+	counter    int64
+}
+
+// NewTraceSequence creates the Trace generator which generates nums in order,
+// and starts over at the end.
+func NewTraceSequence(nums []int64) *Trace {
+	return &Trace{nums: nums}
+}
+
+// NewTraceFrequency creates the Trace generator which generates nums randomly,
+// each with the probability proportional to its weight.
+func NewTraceFrequency(nums []int64, weights []int64) *Trace {
+	cumWeights := make([]int64, len(weights))
+	var sum int64
+	for i, w := range weights {
+		sum += w
+		cumWeights[i] = sum
+	}
+	return &Trace{nums: nums, cumWeights: cumWeights}
+}
+
+// NewTraceFromFile creates the Trace generator from the file of the type.
+// Every line of the file holds a key, followed by its frequency for
+// TraceFrequency, 1 by default, or by anything else such as the access time
+// for TraceSequence, separated by a comma or spaces. The distinct keys are
+// mapped to the integers in [lb, ub] in the order they first appear, and
+// start over from lb if there are more keys.
+func NewTraceFromFile(name string, typ string, lb int64, ub int64) (*Trace, error) {
+	if typ != TraceFrequency && typ != TraceSequence {
+		return nil, fmt.Errorf("unknown trace type %s", typ)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	interval := ub - lb + 1
+	mapped := make(map[string]int)
+	var nums, weights []int64
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.FieldsFunc(s.Text(), func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		weight := int64(1)
+		if typ == TraceFrequency && len(fields) > 1 {
+			if weight, err = strconv.ParseInt(fields[1], 10, 64); err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid frequency at line %d of %s: %s", line, name, fields[1])
+			}
+		}
+
+		i, ok := mapped[fields[0]]
+		if !ok {
+			i = len(mapped)
+			mapped[fields[0]] = i
+			if typ == TraceFrequency {
+				nums = append(nums, lb+int64(i)%interval)
+				weights = append(weights, 0)
+			}
+		}
+		if typ == TraceFrequency {
+			weights[i] += weight
+		} else {
+			nums = append(nums, lb+int64(i)%interval)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(nums) == 0 {
+		return nil, fmt.Errorf("no key in %s", name)
+	}
+
+	if typ == TraceSequence {
+		return NewTraceSequence(nums), nil
+	}
+	t := NewTraceFrequency(nums, weights)
+	if t.cumWeights[len(t.cumWeights)-1] == 0 {
+		return nil, fmt.Errorf("all the frequencies in %s are 0", name)
+	}
+	return t, nil
+}
+
+// Next implements the Generator Next interface.
+func (t *Trace) Next(r *rand.Rand) int64 {
+	var n int64
+	if t.cumWeights == nil {
+		i := atomic.AddInt64(&t.counter, 1) - 1
+		n = t.nums[i%int64(len(t.nums))]
+	} else {
+		x := r.Int63n(t.cumWeights[len(t.cumWeights)-1])
+		n = t.nums[sort.Search(len(t.cumWeights), func(i int) bool {
+			return t.cumWeights[i] > x
+		})]
+	}
+	t.SetLastValue(n)
+	return n
+}
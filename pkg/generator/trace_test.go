@@ -0,0 +1,69 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTrace(t *testing.T, data string) string {
+	name := filepath.Join(t.TempDir(), "trace")
+	if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestTraceSequence(t *testing.T) {
+	name := writeTrace(t, "# key,time\nb,1\na,2\nb,3\n\nc 4\n")
+	g, err := NewTraceFromFile(name, TraceSequence, 10, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// b, a and c are mapped to 10, 11 and 10 again.
+	want := []int64{10, 11, 10, 10, 10, 11}
+	for i, w := range want {
+		if n := g.Next(nil); n != w {
+			t.Fatalf("want %d at %d, but got %d", w, i, n)
+		}
+	}
+}
+
+func TestTraceFrequency(t *testing.T) {
+	name := writeTrace(t, "hot,90\ncold,10\ncold\n")
+	g, err := NewTraceFromFile(name, TraceFrequency, 0, 99)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	var hot int
+	for i := 0; i < 10000; i++ {
+		if g.Next(r) == 0 {
+			hot++
+		}
+	}
+	// hot takes 90 of the 101 accesses.
+	if hot < 8700 || hot > 9100 {
+		t.Fatalf("want about 8911 hot keys, but got %d", hot)
+	}
+
+	if _, err := NewTraceFromFile(writeTrace(t, "a,x\n"), TraceFrequency, 0, 99); err == nil {
+		t.Fatal("invalid frequency should fail")
+	}
+}
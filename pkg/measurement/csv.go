@@ -1,54 +1,322 @@
 package measurement
 
 import (
+	"bufio"
+	"compress/gzip"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
 )
 
+// csvBatchSize is the number of rows handed to the background writer at once.
+const csvBatchSize = 4096
+
 type csventry struct {
+	op string
 	// start time of the operation in us from unix epoch
 	startUs int64
 	// latency of the operation in us
 	latencyUs int64
 }
 
+// csvBatch is a batch of rows for the background writer. If flush is set,
+// the writer flushes all the rows written so far to the file.
+type csvBatch struct {
+	rows  []csventry
+	flush bool
+}
+
+// csvQueue hands the batches to the background writer without blocking the
+// sender, which holds the measurement lock. It only grows if the writer falls
+// behind the workers.
+type csvQueue struct {
+	mu      sync.Mutex
+	batches []csvBatch
+	closed  bool
+	ready   chan struct{}
+}
+
+func newCSVQueue() *csvQueue {
+	return &csvQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *csvQueue) wake() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *csvQueue) push(b csvBatch) {
+	q.mu.Lock()
+	q.batches = append(q.batches, b)
+	q.mu.Unlock()
+	q.wake()
+}
+
+func (q *csvQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.wake()
+}
+
+// pop blocks until there are batches and returns all of them, or returns nil
+// once the queue is closed and empty.
+func (q *csvQueue) pop() []csvBatch {
+	for {
+		q.mu.Lock()
+		batches, closed := q.batches, q.closed
+		q.batches = nil
+		q.mu.Unlock()
+		if len(batches) > 0 || closed {
+			return batches
+		}
+		<-q.ready
+	}
+}
+
+// csvs streams every measured operation as a row to measurement.output_file
+// through a background writer, so the memory doesn't grow with the run and
+// a crash loses only the rows since the last summary.
 type csvs struct {
-	opCsv map[string][]csventry
+	batch []csventry
+	// queue is nil once the stream is finished.
+	queue *csvQueue
+	done  chan error
 }
 
 func (c *csvs) GenerateExtendedOutputs() {
 }
 
-func InitCSV() *csvs {
-	return &csvs{
-		opCsv: make(map[string][]csventry),
+// InitCSV creates the raw measurer and starts its background writer.
+func InitCSV(p *properties.Properties) (*csvs, error) {
+	w, err := newCSVWriter(p)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &csvs{
+		batch: make([]csventry, 0, csvBatchSize),
+		queue: newCSVQueue(),
+		done:  make(chan error, 1),
 	}
+	go func(queue *csvQueue) {
+		c.done <- w.run(queue)
+	}(c.queue)
+	return c, nil
 }
 
 func (c *csvs) Measure(op string, start time.Time, lan time.Duration) {
-	c.opCsv[op] = append(c.opCsv[op], csventry{
+	// the stream is finished, e.g. by a late operation after Output.
+	if c.queue == nil {
+		return
+	}
+	c.batch = append(c.batch, csventry{
+		op:        op,
 		startUs:   start.UnixMicro(),
 		latencyUs: lan.Microseconds(),
 	})
+	if len(c.batch) == csvBatchSize {
+		c.send(false)
+	}
+}
+
+func (c *csvs) send(flush bool) {
+	c.queue.push(csvBatch{rows: c.batch, flush: flush})
+	c.batch = make([]csventry, 0, csvBatchSize)
 }
 
+// Output finishes the stream and waits for the writer, the rows are written
+// to measurement.output_file during the run instead of w.
 func (c *csvs) Output(w io.Writer) error {
-	_, err := fmt.Fprintln(w, "operation,timestamp_us,latency_us")
-	if err != nil {
+	if c.queue == nil {
+		return nil
+	}
+
+	c.send(false)
+	c.queue.close()
+	c.queue = nil
+	return <-c.done
+}
+
+// Summary makes the writer flush the rows measured so far to the file, it
+// doesn't wait for the flush.
+func (c *csvs) Summary() {
+	if c.queue != nil {
+		c.send(true)
+	}
+}
+
+// csvFiles holds the index of the last file of every measurement.output_file
+// written by the process, so that the later measurements, e.g. the trials of
+// a search, append the rows to it instead of truncating it.
+var csvFiles = struct {
+	sync.Mutex
+	index map[string]int
+}{index: make(map[string]int)}
+
+// csvWriter writes the rows to measurement.output_file, or stdout if it is
+// not set, where the rows interleave with the progress report. It compresses
+// the rows with gzip if measurement.raw.gzip is set, and starts a new file
+// every measurement.raw.rotatesize MB.
+type csvWriter struct {
+	name       string
+	compress   bool
+	rotateSize int64
+
+	index int
+	f     *os.File
+	size  countingWriter
+	gz    *gzip.Writer
+	w     *bufio.Writer
+
+	// appending is set if the file is written by an earlier measurement.
+	appending bool
+}
+
+func newCSVWriter(p *properties.Properties) (*csvWriter, error) {
+	w := &csvWriter{
+		name:       p.GetString(prop.MeasurementRawOutputFile, ""),
+		compress:   p.GetBool(prop.MeasurementRawGzip, prop.MeasurementRawGzipDefault),
+		rotateSize: p.GetInt64(prop.MeasurementRawRotateSize, 0) << 20,
+	}
+	if w.name != "" {
+		csvFiles.Lock()
+		w.index, w.appending = csvFiles.index[w.name]
+		csvFiles.Unlock()
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// fileName returns the name of the index-th file, the rotated files get the
+// index before the extension, e.g. raw.csv, raw.1.csv, raw.2.csv.
+func (w *csvWriter) fileName() string {
+	if w.index == 0 {
+		return w.name
+	}
+	ext := filepath.Ext(w.name)
+	return strings.TrimSuffix(w.name, ext) + "." + strconv.Itoa(w.index) + ext
+}
+
+func (w *csvWriter) open() error {
+	var out io.Writer = os.Stdout
+	if w.name != "" {
+		flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if w.appending {
+			flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(w.fileName(), flag, 0666)
+		if err != nil {
+			return err
+		}
+		w.f = f
+		out = f
+
+		csvFiles.Lock()
+		csvFiles.index[w.name] = w.index
+		csvFiles.Unlock()
+	}
+
+	w.size = countingWriter{w: out}
+	out = &w.size
+	if w.compress {
+		w.gz = gzip.NewWriter(out)
+		out = w.gz
+	}
+	w.w = bufio.NewWriter(out)
+	// the appended rows follow the header of the earlier measurement, the
+	// gzip streams are concatenated.
+	if w.appending {
+		w.appending = false
+		return nil
+	}
+	_, err := fmt.Fprintln(w.w, "operation,timestamp_us,latency_us")
+	return err
+}
+
+func (w *csvWriter) flush() error {
+	if err := w.w.Flush(); err != nil {
 		return err
 	}
-	for op, entries := range c.opCsv {
-		for _, entry := range entries {
-			_, err := fmt.Fprintf(w, "%s,%d,%d\n", op, entry.startUs, entry.latencyUs)
-			if err != nil {
-				return err
-			}
+	if w.gz != nil {
+		return w.gz.Flush()
+	}
+	return nil
+}
+
+func (w *csvWriter) close() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			return err
 		}
 	}
+	if w.f != nil {
+		return w.f.Close()
+	}
 	return nil
 }
 
-func (c *csvs) Summary() {
-	// do nothing as csvs don't keep a summary
+func (w *csvWriter) write(rows []csventry) error {
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(w.w, "%s,%d,%d\n", row.op, row.startUs, row.latencyUs); err != nil {
+			return err
+		}
+	}
+
+	if w.rotateSize <= 0 || w.f == nil || w.size.n < w.rotateSize {
+		return nil
+	}
+	if err := w.close(); err != nil {
+		return err
+	}
+	w.index++
+	return w.open()
+}
+
+// run writes the batches until the queue is closed. After an error, it
+// drops the remaining batches and returns the error.
+func (w *csvWriter) run(queue *csvQueue) error {
+	var err error
+	for batches := queue.pop(); batches != nil; batches = queue.pop() {
+		for _, b := range batches {
+			if err == nil {
+				err = w.write(b.rows)
+			}
+			if b.flush && err == nil {
+				err = w.flush()
+			}
+		}
+	}
+	if err != nil {
+		w.close()
+		return err
+	}
+	return w.close()
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
@@ -0,0 +1,108 @@
+package measurement
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+func countRows(t *testing.T, name string, compressed bool) int {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if compressed {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+
+	s := bufio.NewScanner(r)
+	if !s.Scan() || s.Text() != "operation,timestamp_us,latency_us" {
+		t.Fatalf("%s has no header", name)
+	}
+	n := 0
+	for s.Scan() {
+		n++
+	}
+	return n
+}
+
+func TestCSVStream(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "raw.csv.gz")
+	p := properties.NewProperties()
+	p.Set(prop.MeasurementType, "csv")
+	p.Set(prop.MeasurementRawOutputFile, outFile)
+	p.Set(prop.MeasurementRawGzip, "true")
+	InitMeasure(p)
+
+	for i := 0; i < 10; i++ {
+		Measure("READ", time.Now(), time.Millisecond)
+	}
+	// the rows are in the file soon after a summary, before the run
+	// finishes.
+	Summary()
+	n := 0
+	for i := 0; i < 100 && n != 10; i++ {
+		time.Sleep(10 * time.Millisecond)
+		n = countRows(t, outFile, true)
+	}
+	if n != 10 {
+		t.Fatalf("want 10 rows after the summary, but got %d", n)
+	}
+
+	Measure("READ", time.Now(), time.Millisecond)
+	Output()
+	if n := countRows(t, outFile, true); n != 11 {
+		t.Fatalf("want 11 rows after the output, but got %d", n)
+	}
+	// the operations after the output are dropped.
+	Measure("READ", time.Now(), time.Millisecond)
+	Summary()
+
+	// a later measurement of the process, e.g. the next trial of a search,
+	// appends to the file.
+	InitMeasure(p)
+	Measure("READ", time.Now(), time.Millisecond)
+	Output()
+	if n := countRows(t, outFile, true); n != 12 {
+		t.Fatalf("want 12 rows after the next measurement, but got %d", n)
+	}
+}
+
+func TestCSVRotate(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "raw.csv")
+	p := properties.NewProperties()
+	p.Set(prop.MeasurementType, "csv")
+	p.Set(prop.MeasurementRawOutputFile, outFile)
+	p.Set(prop.MeasurementRawRotateSize, "1")
+	InitMeasure(p)
+
+	// every row takes about 30 bytes, so the rows take 2 files of 1MB.
+	const rows = 50000
+	for i := 0; i < rows; i++ {
+		Measure("READ", time.Now(), time.Millisecond)
+	}
+	Output()
+
+	first := countRows(t, outFile, false)
+	second := countRows(t, filepath.Join(filepath.Dir(outFile), "raw.1.csv"), false)
+	if first == 0 || second == 0 || first+second != rows {
+		t.Fatalf("want %d rows in 2 files, but got %d and %d", rows, first, second)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(outFile), "raw.2.csv")); err == nil {
+		t.Fatalf("too many files are rotated")
+	}
+}
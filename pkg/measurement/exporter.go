@@ -0,0 +1,157 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// Exporter names.
+const (
+	ExporterJSON = "json"
+	ExporterText = "text"
+)
+
+// metricNames is the order of the metrics in the text export.
+var metricNames = []string{ELAPSED, COUNT, QPS, AVG, MIN, MAX, PER50TH, PER90TH, PER95TH, PER99TH, PER999TH, PER9999TH}
+
+// jsonExporter writes the result as a JSON object.
+type jsonExporter struct{}
+
+func (jsonExporter) Export(w io.Writer, result *ycsb.Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// textExporter writes the metadata as `name: value` lines followed by a line
+// of `[OPERATION], METRIC, value` for every metric, like Java YCSB.
+type textExporter struct{}
+
+func (textExporter) Export(w io.Writer, result *ycsb.Result) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "label: %s\n", result.Label)
+	fmt.Fprintf(bw, "db: %s\n", result.DB)
+	fmt.Fprintf(bw, "workload: %s\n", result.Workload)
+	fmt.Fprintf(bw, "command: %s\n", result.Command)
+	fmt.Fprintf(bw, "version: %s\n", result.Version)
+	fmt.Fprintf(bw, "start_time: %s\n", result.StartTime.Format(time.RFC3339Nano))
+	fmt.Fprintf(bw, "end_time: %s\n", result.EndTime.Format(time.RFC3339Nano))
+
+	keys := make([]string, 0, len(result.Properties))
+	for k := range result.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(bw, "property: %s=%s\n", k, result.Properties[k])
+	}
+
+	ops := make([]string, 0, len(result.Operations))
+	for op := range result.Operations {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		for _, name := range metricNames {
+			if v, ok := result.Operations[op][name]; ok {
+				fmt.Fprintf(bw, "[%s], %s, %v\n", op, name, v)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// secretKeys are the parts of the property keys whose values are redacted
+// in the export, e.g. mysql.password.
+var secretKeys = []string{"password", "passwd", "secret", "token"}
+
+// redactedValue replaces the values of the secret properties.
+const redactedValue = "<redacted>"
+
+// redact returns the properties with the values of the secret keys replaced.
+func redact(props map[string]string) map[string]string {
+	redacted := make(map[string]string, len(props))
+	for k, v := range props {
+		lower := strings.ToLower(k)
+		for _, secret := range secretKeys {
+			if strings.Contains(lower, secret) {
+				v = redactedValue
+				break
+			}
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+func init() {
+	ycsb.RegisterExporter(ExporterJSON, jsonExporter{})
+	ycsb.RegisterExporter(ExporterText, textExporter{})
+}
+
+// Export writes the result with the measurements of all operations and the
+// properties by the exporter property to the exportfile property, or to
+// stdout if the exportfile is not set. It does nothing if neither is set.
+// The result has no measurements if the measurement type keeps no histograms,
+// and the values of the passwords, secrets and tokens are redacted.
+func Export(result *ycsb.Result) error {
+	p := globalMeasure.p
+	name, hasExporter := p.Get(prop.Exporter)
+	outFile, hasFile := p.Get(prop.ExportFile)
+	if !hasExporter && !hasFile {
+		return nil
+	}
+	if !hasExporter {
+		name = ExporterJSON
+	}
+	exporter := ycsb.GetExporter(name)
+	if exporter == nil {
+		return fmt.Errorf("exporter %s is not registered", name)
+	}
+
+	if globalMeasure.keepsHistograms() {
+		var err error
+		if result.Operations, err = Stats(); err != nil {
+			return err
+		}
+	}
+	if result.Properties == nil {
+		result.Properties = p.Map()
+	}
+	result.Properties = redact(result.Properties)
+
+	if outFile == "" {
+		return exporter.Export(os.Stdout, result)
+	}
+	f, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	if err = exporter.Export(f, result); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
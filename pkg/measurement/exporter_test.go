@@ -0,0 +1,98 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+func TestExport(t *testing.T) {
+	for _, exporter := range []string{ExporterJSON, ExporterText} {
+		outFile := filepath.Join(t.TempDir(), "result")
+		p := properties.NewProperties()
+		p.Set(prop.Exporter, exporter)
+		p.Set(prop.ExportFile, outFile)
+		p.Set("mysql.password", "hunter2")
+		InitMeasure(p)
+		Measure("READ", time.Now(), time.Millisecond)
+
+		if err := Export(&ycsb.Result{Label: "ci", DB: "basic"}); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(string(data), "hunter2") {
+			t.Fatalf("the password is exported:\n%s", data)
+		}
+		if exporter == ExporterText {
+			for _, want := range []string{"label: ci\n", "db: basic\n", "property: exportfile=" + outFile + "\n", "[READ], COUNT, 1\n", "property: mysql.password=" + redactedValue + "\n"} {
+				if !strings.Contains(string(data), want) {
+					t.Fatalf("%q is not exported:\n%s", want, data)
+				}
+			}
+			continue
+		}
+
+		var result ycsb.Result
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("bad export %s: %v", data, err)
+		}
+		if result.Label != "ci" || result.DB != "basic" || result.Properties[prop.Exporter] != exporter {
+			t.Fatalf("metadata is not exported: %s", data)
+		}
+		if result.Operations["READ"][COUNT] != float64(1) {
+			t.Fatalf("READ is not exported: %s", data)
+		}
+	}
+}
+
+func TestExportRaw(t *testing.T) {
+	dir := t.TempDir()
+	outFile := filepath.Join(dir, "result")
+	p := properties.NewProperties()
+	p.Set(prop.MeasurementType, "raw")
+	p.Set(prop.MeasurementRawOutputFile, filepath.Join(dir, "raw"))
+	p.Set(prop.ExportFile, outFile)
+	InitMeasure(p)
+	Measure("READ", time.Now(), time.Millisecond)
+	Output()
+
+	// the raw output has the measurements, the result only the metadata.
+	if err := Export(&ycsb.Result{Label: "ci", DB: "basic"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result ycsb.Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("bad export %s: %v", data, err)
+	}
+	if result.Label != "ci" || len(result.Operations) != 0 {
+		t.Fatalf("want the metadata only, but got %s", data)
+	}
+}
@@ -25,6 +25,10 @@ type histogram struct {
 	boundCounts util.ConcurrentMap
 	startTime   time.Time
 	hist        *hdrhistogram.Histogram
+
+	// interval records the latencies since the last interval summary only.
+	intervalStart time.Time
+	interval      *hdrhistogram.Histogram
 }
 
 // Metric name.
@@ -47,16 +51,31 @@ func newHistogram() *histogram {
 	h := new(histogram)
 	h.startTime = time.Now()
 	h.hist = hdrhistogram.New(1, 24*60*60*1000*1000, 3)
+	h.intervalStart = h.startTime
+	h.interval = hdrhistogram.New(1, 24*60*60*1000*1000, 3)
 	return h
 }
 
 func (h *histogram) Measure(latency time.Duration) {
 	h.hist.RecordValue(latency.Microseconds())
+	h.interval.RecordValue(latency.Microseconds())
 }
 
 func (h *histogram) Summary() []string {
-	res := h.getInfo()
+	return formatInfo(h.getInfo())
+}
+
+// IntervalSummary returns the summary of the latencies since the last
+// interval summary, and starts a new interval.
+func (h *histogram) IntervalSummary() []string {
+	now := time.Now()
+	res := getInfo(h.interval, h.intervalStart, now)
+	h.interval.Reset()
+	h.intervalStart = now
+	return formatInfo(res)
+}
 
+func formatInfo(res map[string]interface{}) []string {
 	return []string{
 		util.FloatToOneString(res[ELAPSED]),
 		util.IntToString(res[COUNT]),
@@ -74,22 +93,26 @@ func (h *histogram) Summary() []string {
 }
 
 func (h *histogram) getInfo() map[string]interface{} {
-	min := h.hist.Min()
-	max := h.hist.Max()
-	avg := int64(h.hist.Mean())
-	count := h.hist.TotalCount()
-
 	bounds := h.boundCounts.Keys()
 	sort.Ints(bounds)
 
-	per50 := h.hist.ValueAtPercentile(50)
-	per90 := h.hist.ValueAtPercentile(90)
-	per95 := h.hist.ValueAtPercentile(95)
-	per99 := h.hist.ValueAtPercentile(99)
-	per999 := h.hist.ValueAtPercentile(99.9)
-	per9999 := h.hist.ValueAtPercentile(99.99)
+	return getInfo(h.hist, h.startTime, time.Now())
+}
+
+func getInfo(hist *hdrhistogram.Histogram, start time.Time, end time.Time) map[string]interface{} {
+	min := hist.Min()
+	max := hist.Max()
+	avg := int64(hist.Mean())
+	count := hist.TotalCount()
+
+	per50 := hist.ValueAtPercentile(50)
+	per90 := hist.ValueAtPercentile(90)
+	per95 := hist.ValueAtPercentile(95)
+	per99 := hist.ValueAtPercentile(99)
+	per999 := hist.ValueAtPercentile(99.9)
+	per9999 := hist.ValueAtPercentile(99.99)
 
-	elapsed := time.Now().Sub(h.startTime).Seconds()
+	elapsed := end.Sub(start).Seconds()
 	qps := float64(count) / elapsed
 	res := make(map[string]interface{})
 	res[ELAPSED] = elapsed
@@ -0,0 +1,41 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalSummary(t *testing.T) {
+	h := newHistogram()
+	for i := 0; i < 100; i++ {
+		h.Measure(time.Millisecond)
+	}
+	if s := h.IntervalSummary(); s[1] != "100" || s[9] != "1000" {
+		t.Fatalf("want 100 ops with p99 1000us in the first interval, but got %v", s)
+	}
+
+	// a spike in the second interval shows in its p99 in full.
+	for i := 0; i < 10; i++ {
+		h.Measure(100 * time.Millisecond)
+	}
+	if s := h.IntervalSummary(); s[1] != "10" || s[9] != "100031" {
+		t.Fatalf("want 10 ops with p99 100031us in the second interval, but got %v", s)
+	}
+
+	if s := h.Summary(); s[1] != "110" {
+		t.Fatalf("want 110 ops in total, but got %v", s)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"time"
 
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/magiconair/properties"
 	"github.com/pingcap/go-ycsb/pkg/prop"
 	"github.com/pingcap/go-ycsb/pkg/util"
@@ -17,9 +18,24 @@ type histograms struct {
 	p *properties.Properties
 
 	histograms map[string]*histogram
+	// hlogs writes the interval histograms, nil if hdrhistogram.fileoutput is not set.
+	hlogs *hlogs
 }
 
 func (h *histograms) GenerateExtendedOutputs() {
+	if h.hlogs != nil {
+		// the last interval since the last summary.
+		now := time.Now()
+		for op, opM := range h.histograms {
+			if err := h.hlogs.write(op, opM.interval, opM.intervalStart, now); err != nil {
+				panic("failed to write histogram log: " + err.Error())
+			}
+		}
+		if err := h.hlogs.close(); err != nil {
+			panic("failed to close histogram log: " + err.Error())
+		}
+	}
+
 	exportHistograms := h.p.GetBool(prop.MeasurementHistogramPercentileExport, prop.MeasurementHistogramPercentileExportDefault)
 	if exportHistograms {
 		exportHistogramsFilepath := h.p.GetString(prop.MeasurementHistogramPercentileExportFilepath, prop.MeasurementHistogramPercentileExportFilepathDefault)
@@ -51,6 +67,34 @@ func (h *histograms) Measure(op string, start time.Time, lan time.Duration) {
 	opM.Measure(lan)
 }
 
+// measureSince measures a latency of the operation recorded since start,
+// which starts the operation if it is new.
+func (h *histograms) measureSince(op string, start time.Time, lan time.Duration) {
+	opM, ok := h.histograms[op]
+	if !ok {
+		opM = newHistogram()
+		opM.startTime = start
+		opM.intervalStart = start
+		h.histograms[op] = opM
+	}
+	opM.Measure(lan)
+}
+
+// merge merges the latencies of the operation recorded since start.
+func (h *histograms) merge(op string, start time.Time, hist *hdrhistogram.Histogram) {
+	opM, ok := h.histograms[op]
+	if !ok {
+		opM = newHistogram()
+		opM.startTime = start
+		opM.intervalStart = start
+		h.histograms[op] = opM
+	} else if start.Before(opM.startTime) {
+		opM.startTime = start
+	}
+	opM.hist.Merge(hist)
+	opM.interval.Merge(hist)
+}
+
 func (h *histograms) summary() map[string][]string {
 	summaries := make(map[string][]string, len(h.histograms))
 	for op, opM := range h.histograms {
@@ -59,12 +103,32 @@ func (h *histograms) summary() map[string][]string {
 	return summaries
 }
 
+func (h *histograms) intervalSummary() map[string][]string {
+	summaries := make(map[string][]string, len(h.histograms))
+	now := time.Now()
+	for op, opM := range h.histograms {
+		if h.hlogs != nil {
+			if err := h.hlogs.write(op, opM.interval, opM.intervalStart, now); err != nil {
+				panic("failed to write histogram log: " + err.Error())
+			}
+		}
+		summaries[op] = opM.IntervalSummary()
+	}
+	return summaries
+}
+
+// Summary prints the measurements of the last interval only, so that a
+// latency spike late in a long run is not averaged away.
 func (h *histograms) Summary() {
-	h.Output(os.Stdout)
+	h.render(os.Stdout, h.intervalSummary())
 }
 
 func (h *histograms) Output(w io.Writer) error {
-	summaries := h.summary()
+	h.render(w, h.summary())
+	return nil
+}
+
+func (h *histograms) render(w io.Writer, summaries map[string][]string) {
 	keys := make([]string, 0, len(summaries))
 	for k := range summaries {
 		keys = append(keys, k)
@@ -89,7 +153,6 @@ func (h *histograms) Output(w io.Writer) error {
 	default:
 		panic("unsupported outputstyle: " + outputStyle)
 	}
-	return nil
 }
 
 func InitHistograms(p *properties.Properties) *histograms {
@@ -0,0 +1,145 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// hlogMaxValueUnitRatio scales the max latency in us of an interval to
+// seconds, like Java YCSB.
+const hlogMaxValueUnitRatio = 1000000.0
+
+// hlogFiles are the interval logs written by the process, so that the later
+// measurements, e.g. the trials of a search, append their intervals to them
+// instead of truncating them.
+var hlogFiles = struct {
+	sync.Mutex
+	written map[string]bool
+}{written: make(map[string]bool)}
+
+type hlog struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+// hlogs writes the interval histograms of every operation to its own
+// HdrHistogram interval log, <path><OP>.hlog, which can be read by tools
+// such as HdrHistogramVisualizer, or merged with the logs of other clients.
+type hlogs struct {
+	path      string
+	startTime time.Time
+	logs      map[string]*hlog
+}
+
+func newHlogs(path string) *hlogs {
+	return &hlogs{
+		path:      path,
+		startTime: time.Now(),
+		logs:      make(map[string]*hlog, 16),
+	}
+}
+
+func (l *hlogs) open(op string) (*hlog, error) {
+	if lg, ok := l.logs[op]; ok {
+		return lg, nil
+	}
+
+	outFile := fmt.Sprintf("%s%s.hlog", l.path, op)
+	hlogFiles.Lock()
+	appending := hlogFiles.written[outFile]
+	hlogFiles.written[outFile] = true
+	hlogFiles.Unlock()
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appending {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(outFile, flag, 0666)
+	if err != nil {
+		return nil, err
+	}
+	lg := &hlog{f: f, w: bufio.NewWriter(f)}
+	l.logs[op] = lg
+
+	// the appended intervals follow a new start and base time, which the
+	// readers take in the middle of the log too.
+	lw := hdrhistogram.NewHistogramLogWriter(lg.w)
+	if !appending {
+		if err = lw.OutputLogFormatVersion(); err != nil {
+			return nil, err
+		}
+	}
+	startMs := l.startTime.UnixMilli()
+	if err = lw.OutputStartTime(startMs); err != nil {
+		return nil, err
+	}
+	if err = lw.OutputBaseTime(startMs); err != nil {
+		return nil, err
+	}
+	if appending {
+		return lg, nil
+	}
+	return lg, lw.OutputLegend()
+}
+
+// baseTime is the base time of the interval timestamps, the log writer
+// writes it in whole seconds.
+func (l *hlogs) baseTime() time.Time {
+	return time.Unix(l.startTime.Unix(), 0)
+}
+
+// write appends the interval histogram of the operation from start to end.
+// The line is not written by HistogramLogWriter.OutputIntervalHistogram,
+// which writes the start and the end time in ms in hdrhistogram-go v1.1.2,
+// where the log format has the start time and the interval length in
+// seconds.
+func (l *hlogs) write(op string, hist *hdrhistogram.Histogram, start time.Time, end time.Time) error {
+	lg, err := l.open(op)
+	if err != nil {
+		return err
+	}
+
+	data, err := hist.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(lg.w, "%.3f,%.3f,%.3f,%s\n",
+		start.Sub(l.baseTime()).Seconds(),
+		end.Sub(start).Seconds(),
+		float64(hist.Max())/hlogMaxValueUnitRatio,
+		data); err != nil {
+		return err
+	}
+	return lg.w.Flush()
+}
+
+func (l *hlogs) close() error {
+	for op, lg := range l.logs {
+		if err := lg.w.Flush(); err != nil {
+			return err
+		}
+		if err := lg.f.Close(); err != nil {
+			return err
+		}
+		delete(l.logs, op)
+	}
+	return nil
+}
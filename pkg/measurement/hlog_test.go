@@ -0,0 +1,71 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+func TestHistogramLog(t *testing.T) {
+	dir := t.TempDir()
+	p := properties.NewProperties()
+	p.Set(prop.HdrHistogramFileOutput, "true")
+	p.Set(prop.HdrHistogramOutputPath, dir+string(filepath.Separator))
+	p.Set(prop.MeasurementRawOutputFile, filepath.Join(dir, "output"))
+	InitMeasure(p)
+
+	for i := 0; i < 10; i++ {
+		Measure("READ", time.Now(), time.Millisecond)
+	}
+	Summary()
+	for i := 0; i < 5; i++ {
+		Measure("READ", time.Now(), 2*time.Millisecond)
+	}
+	Output()
+
+	// a later measurement of the process, e.g. the next trial of a search,
+	// appends to the log.
+	InitMeasure(p)
+	Measure("READ", time.Now(), time.Millisecond)
+	Output()
+
+	f, err := os.Open(filepath.Join(dir, "READ.hlog"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r := hdrhistogram.NewHistogramLogReader(f)
+	var counts []int64
+	for {
+		hist, err := r.NextIntervalHistogram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hist == nil {
+			break
+		}
+		counts = append(counts, hist.TotalCount())
+	}
+	if len(counts) != 3 || counts[0] != 10 || counts[1] != 5 || counts[2] != 1 {
+		t.Fatalf("want intervals of 10, 5 and 1 reads, but got %v", counts)
+	}
+}
@@ -15,11 +15,13 @@ package measurement
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/magiconair/properties"
 	"github.com/pingcap/go-ycsb/pkg/prop"
 	"github.com/pingcap/go-ycsb/pkg/ycsb"
@@ -28,22 +30,208 @@ import (
 var header = []string{"Operation", "Takes(s)", "Count", "OPS", "Avg(us)", "Min(us)", "Max(us)", "50th(us)", "90th(us)", "95th(us)", "99th(us)", "99.9th(us)", "99.99th(us)"}
 
 type measurement struct {
-	sync.RWMutex
+	sync.Mutex
 
 	p *properties.Properties
 
 	measurer ycsb.Measurer
+	// shards record the latencies of the workers until they are drained,
+	// one for every worker.
+	shards []*shard
+
+	// phase records the measurements of the current phase of the target
+	// schedule separately, nil if there is no phase in progress.
+	phase      *histograms
+	phaseName  string
+	phaseStart time.Time
+
+	// sent holds the histograms of the last interval snapshot.
+	sent map[string]*sentHistogram
+}
+
+// sentHistogram is the cumulative histogram of an operation at the last
+// interval snapshot, the next one carries the counts recorded since.
+type sentHistogram struct {
+	at     time.Time
+	counts *hdrhistogram.Snapshot
 }
 
 func (m *measurement) measure(op string, start time.Time, lan time.Duration) {
 	m.Lock()
 	m.measurer.Measure(op, start, lan)
+	if m.phase != nil {
+		m.phase.Measure(op, start, lan)
+	}
+	m.Unlock()
+}
+
+func (m *measurement) percentile(op string, percentile float64) (int64, float64, bool) {
+	m.Lock()
+	defer m.Unlock()
+	m.drain()
+
+	h, ok := m.measurer.(*histograms)
+	if !ok {
+		return 0, 0, false
+	}
+	opM, ok := h.histograms[op]
+	if !ok {
+		return 0, 0, false
+	}
+	qps := float64(opM.hist.TotalCount()) / time.Now().Sub(opM.startTime).Seconds()
+	return opM.hist.ValueAtPercentile(percentile), qps, true
+}
+
+func (m *measurement) keepsHistograms() bool {
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.measurer.(*histograms)
+	return ok
+}
+
+func (m *measurement) stats() (map[string]map[string]interface{}, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.drain()
+
+	h, ok := m.measurer.(*histograms)
+	if !ok {
+		return nil, fmt.Errorf("measurement type %T keeps no histograms", m.measurer)
+	}
+
+	stats := make(map[string]map[string]interface{}, len(h.histograms))
+	for op, opM := range h.histograms {
+		stats[op] = opM.getInfo()
+	}
+	return stats, nil
+}
+
+func (m *measurement) snapshot() (map[string]HistogramSnapshot, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.drain()
+
+	h, ok := m.measurer.(*histograms)
+	if !ok {
+		return nil, fmt.Errorf("measurement type %T keeps no histograms", m.measurer)
+	}
+
+	snapshots := make(map[string]HistogramSnapshot, len(h.histograms))
+	for op, opM := range h.histograms {
+		data, err := opM.hist.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+		if err != nil {
+			return nil, err
+		}
+		snapshots[op] = HistogramSnapshot{StartTime: opM.startTime, Data: string(data)}
+	}
+	return snapshots, nil
+}
+
+func (m *measurement) intervalSnapshot() (map[string]HistogramSnapshot, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.drain()
+
+	h, ok := m.measurer.(*histograms)
+	if !ok {
+		return nil, fmt.Errorf("measurement type %T keeps no histograms", m.measurer)
+	}
+	if m.sent == nil {
+		m.sent = make(map[string]*sentHistogram, len(h.histograms))
+	}
+
+	now := time.Now()
+	snapshots := make(map[string]HistogramSnapshot, len(h.histograms))
+	for op, opM := range h.histograms {
+		cur := opM.hist.Export()
+		last, ok := m.sent[op]
+		if !ok {
+			last = &sentHistogram{at: opM.startTime}
+			m.sent[op] = last
+		}
+
+		interval := &hdrhistogram.Snapshot{
+			LowestTrackableValue:  cur.LowestTrackableValue,
+			HighestTrackableValue: cur.HighestTrackableValue,
+			SignificantFigures:    cur.SignificantFigures,
+			Counts:                append([]int64(nil), cur.Counts...),
+		}
+		if last.counts != nil {
+			for i, n := range last.counts.Counts {
+				interval.Counts[i] -= n
+			}
+		}
+
+		data, err := hdrhistogram.Import(interval).Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+		if err != nil {
+			return nil, err
+		}
+		snapshots[op] = HistogramSnapshot{StartTime: last.at, Data: string(data)}
+		last.at = now
+		last.counts = cur
+	}
+	return snapshots, nil
+}
+
+func (m *measurement) merge(snapshots map[string]HistogramSnapshot) error {
+	m.Lock()
+	defer m.Unlock()
+
+	h, ok := m.measurer.(*histograms)
+	if !ok {
+		return fmt.Errorf("measurement type %T keeps no histograms", m.measurer)
+	}
+
+	for op, snapshot := range snapshots {
+		hist, err := hdrhistogram.Decode([]byte(snapshot.Data))
+		if err != nil {
+			return err
+		}
+
+		h.merge(op, snapshot.StartTime, hist)
+	}
+	return nil
+}
+
+func (m *measurement) startPhase(name string) {
+	m.Lock()
+	// the buffered measurements belong to the previous phase.
+	m.drain()
+	m.phase = InitHistograms(m.p)
+	m.phaseName = name
+	m.phaseStart = time.Now()
 	m.Unlock()
 }
 
+func (m *measurement) finishPhase() {
+	m.Lock()
+	defer m.Unlock()
+	m.drain()
+
+	if m.phase == nil {
+		return
+	}
+
+	fmt.Printf("***************** phase %s finished, takes %s *****************\n",
+		m.phaseName, time.Now().Sub(m.phaseStart).Round(time.Millisecond))
+	m.phase.Output(os.Stdout)
+	m.phase = nil
+}
+
 func (m *measurement) output() {
-	m.RLock()
-	defer m.RUnlock()
+	m.Lock()
+	defer m.Unlock()
+	m.drain()
+
+	m.measurer.GenerateExtendedOutputs()
+
+	// the raw measurement streams the rows to the output file during the run.
+	if c, ok := m.measurer.(*csvs); ok {
+		if err := c.Output(nil); err != nil {
+			panic("failed to write output: " + err.Error())
+		}
+		return
+	}
 
 	outFile := m.p.GetString(prop.MeasurementRawOutputFile, "")
 	var w *bufio.Writer
@@ -70,9 +258,11 @@ func (m *measurement) output() {
 }
 
 func (m *measurement) summary() {
-	m.RLock()
+	// the summary starts a new interval, which changes the measurer.
+	m.Lock()
+	m.drain()
 	globalMeasure.measurer.Summary()
-	m.RUnlock()
+	m.Unlock()
 }
 
 // InitMeasure initializes the global measurement.
@@ -82,9 +272,19 @@ func InitMeasure(p *properties.Properties) {
 	measurementType := p.GetString(prop.MeasurementType, prop.MeasurementTypeDefault)
 	switch measurementType {
 	case "histogram":
-		globalMeasure.measurer = InitHistograms(p)
+		h := InitHistograms(p)
+		if p.GetBool(prop.HdrHistogramFileOutput, prop.HdrHistogramFileOutputDefault) {
+			h.hlogs = newHlogs(p.GetString(prop.HdrHistogramOutputPath, prop.HdrHistogramOutputPathDefault))
+		}
+		globalMeasure.measurer = h
 	case "raw", "csv":
-		globalMeasure.measurer = InitCSV()
+		c, err := InitCSV(p)
+		if err != nil {
+			panic("failed to create output file: " + err.Error())
+		}
+		globalMeasure.measurer = c
+	case "timeseries":
+		globalMeasure.measurer = InitTimeSeries(p)
 	default:
 		panic("unsupported measurement type: " + measurementType)
 	}
@@ -93,15 +293,68 @@ func InitMeasure(p *properties.Properties) {
 
 // Output prints the complete measurements.
 func Output() {
-	globalMeasure.measurer.GenerateExtendedOutputs()
 	globalMeasure.output()
 }
 
-// Summary prints the measurement summary.
+// Summary prints the measurement summary of the last interval.
 func Summary() {
 	globalMeasure.summary()
 }
 
+// Percentile returns the latency in us at the percentile and the throughput
+// in ops/sec of the operation measured so far. It returns false if the
+// operation is not measured or the measurement type keeps no histograms.
+func Percentile(op string, percentile float64) (latency int64, qps float64, ok bool) {
+	return globalMeasure.percentile(op, percentile)
+}
+
+// Stats returns the metrics of all operations measured so far, keyed by the
+// metric names such as COUNT and PER99TH. It fails if the measurement type
+// keeps no histograms.
+func Stats() (map[string]map[string]interface{}, error) {
+	return globalMeasure.stats()
+}
+
+// HistogramSnapshot is a serializable copy of the histogram of an operation.
+type HistogramSnapshot struct {
+	// StartTime is when the first operation was measured, or when the
+	// interval of an interval snapshot started.
+	StartTime time.Time `json:"start_time"`
+	// Data is the histogram in the base64 encoded HdrHistogram V2 compressed format.
+	Data string `json:"data"`
+}
+
+// Snapshot returns the copies of the histograms of all operations measured so
+// far. It fails if the measurement type keeps no histograms.
+func Snapshot() (map[string]HistogramSnapshot, error) {
+	return globalMeasure.snapshot()
+}
+
+// IntervalSnapshot returns the copies of the histograms of all operations
+// measured since the last interval snapshot, the first one covers all the
+// operations measured so far. It fails if the measurement type keeps no
+// histograms.
+func IntervalSnapshot() (map[string]HistogramSnapshot, error) {
+	return globalMeasure.intervalSnapshot()
+}
+
+// Merge merges the histogram snapshots, e.g. measured by other processes,
+// into the current measurement.
+func Merge(snapshots map[string]HistogramSnapshot) error {
+	return globalMeasure.merge(snapshots)
+}
+
+// StartPhase starts a phase of the target schedule, the following
+// measurements are also summarized for this phase.
+func StartPhase(name string) {
+	globalMeasure.startPhase(name)
+}
+
+// FinishPhase prints the summary of the current phase, if any, and stops it.
+func FinishPhase() {
+	globalMeasure.finishPhase()
+}
+
 // EnableWarmUp sets whether to enable warm-up.
 func EnableWarmUp(b bool) {
 	if b {
@@ -0,0 +1,168 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// PrometheusPath is the path of the Prometheus metrics.
+const PrometheusPath = "/metrics"
+
+// errorSuffix marks the operations which failed, followed by the error class
+// in the classified ones, see client.DbWrapper.
+const errorSuffix = "_ERROR"
+
+// intendedSuffix marks the intended latencies of the operations, measured
+// from their scheduled start, see client.DbWrapper.
+const intendedSuffix = "_INTENDED"
+
+// totalOp is measured with every operation, it is not exported since it
+// would count every operation twice.
+const totalOp = "TOTAL"
+
+// dbOps are the DB operations measured by client.DbWrapper. The other
+// measurements, e.g. the retries, transactions and anomalies measured by the
+// middlewares and workloads, are exported as the events, so that summing the
+// operations counts every DB operation once.
+var dbOps = map[string]bool{
+	"READ":         true,
+	"BATCH_READ":   true,
+	"SCAN":         true,
+	"UPDATE":       true,
+	"BATCH_UPDATE": true,
+	"INSERT":       true,
+	"BATCH_INSERT": true,
+	"DELETE":       true,
+	"BATCH_DELETE": true,
+	"BEGIN":        true,
+	"COMMIT":       true,
+	"ABORT":        true,
+	"QUERY":        true,
+}
+
+var prometheusQuantiles = []float64{50, 90, 95, 99, 99.9, 99.99}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type prometheusMetric struct {
+	name  string
+	help  string
+	typ   string
+	lines []string
+}
+
+func (m *prometheusMetric) add(suffix string, labels string, v interface{}) {
+	m.lines = append(m.lines, fmt.Sprintf("%s%s{%s} %v", m.name, suffix, labels, v))
+}
+
+func (m *measurement) writePrometheus(w io.Writer) error {
+	m.Lock()
+	defer m.Unlock()
+	m.drain()
+
+	h, ok := m.measurer.(*histograms)
+	if !ok {
+		return fmt.Errorf("measurement type %T keeps no histograms", m.measurer)
+	}
+
+	ops := make([]string, 0, len(h.histograms))
+	for op := range h.histograms {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	total := &prometheusMetric{name: "ycsb_operations_total", help: "Number of the measured operations.", typ: "counter"}
+	errs := &prometheusMetric{name: "ycsb_errors_total", help: "Number of the failed operations.", typ: "counter"}
+	classes := &prometheusMetric{name: "ycsb_classified_errors_total", help: "Number of the failed operations by the error class.", typ: "counter"}
+	latency := &prometheusMetric{name: "ycsb_latency_microseconds", help: "Latency of the measured operations in us.", typ: "summary"}
+	intended := &prometheusMetric{name: "ycsb_intended_latency_microseconds", help: "Latency of the measured operations in us since their scheduled start.", typ: "summary"}
+	events := &prometheusMetric{name: "ycsb_events_total", help: "Number of the measured events other than the operations.", typ: "counter"}
+	eventLatency := &prometheusMetric{name: "ycsb_event_latency_microseconds", help: "Latency of the measured events other than the operations in us.", typ: "summary"}
+	addLatency := func(m *prometheusMetric, label string, hist *hdrhistogram.Histogram) {
+		for _, q := range prometheusQuantiles {
+			m.add("", fmt.Sprintf(`%s,quantile="%v"`, label, q/100), hist.ValueAtPercentile(q))
+		}
+		m.add("_sum", label, int64(hist.Mean()*float64(hist.TotalCount())))
+		m.add("_count", label, hist.TotalCount())
+	}
+	for _, op := range ops {
+		hist := h.histograms[op].hist
+		if op == totalOp || op == totalOp+intendedSuffix {
+			continue
+		}
+		if i := strings.Index(op, errorSuffix+"_"); i >= 0 && dbOps[op[:i]] {
+			label := fmt.Sprintf(`operation="%s",class="%s"`,
+				labelEscaper.Replace(op[:i]), labelEscaper.Replace(op[i+len(errorSuffix)+1:]))
+			classes.add("", label, hist.TotalCount())
+			continue
+		}
+		if strings.HasSuffix(op, errorSuffix) && dbOps[strings.TrimSuffix(op, errorSuffix)] {
+			label := fmt.Sprintf(`operation="%s"`, labelEscaper.Replace(strings.TrimSuffix(op, errorSuffix)))
+			errs.add("", label, hist.TotalCount())
+			continue
+		}
+
+		if strings.HasSuffix(op, intendedSuffix) && dbOps[strings.TrimSuffix(op, intendedSuffix)] {
+			label := fmt.Sprintf(`operation="%s"`, labelEscaper.Replace(strings.TrimSuffix(op, intendedSuffix)))
+			addLatency(intended, label, hist)
+			continue
+		}
+
+		if !dbOps[op] {
+			label := fmt.Sprintf(`event="%s"`, labelEscaper.Replace(op))
+			events.add("", label, hist.TotalCount())
+			addLatency(eventLatency, label, hist)
+			continue
+		}
+
+		label := fmt.Sprintf(`operation="%s"`, labelEscaper.Replace(op))
+		total.add("", label, hist.TotalCount())
+		addLatency(latency, label, hist)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, metric := range []*prometheusMetric{total, errs, classes, latency, intended, events, eventLatency} {
+		fmt.Fprintf(bw, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(bw, "# TYPE %s %s\n", metric.name, metric.typ)
+		for _, line := range metric.lines {
+			fmt.Fprintln(bw, line)
+		}
+	}
+	return bw.Flush()
+}
+
+// PrometheusHandler returns the HTTP handler which exposes the counters, the
+// error counters and the latency summaries of all operations measured so far
+// in the Prometheus text format.
+func PrometheusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if globalMeasure == nil {
+			http.Error(w, "measurement is not initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := globalMeasure.writePrometheus(w); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		}
+	})
+}
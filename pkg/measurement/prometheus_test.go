@@ -0,0 +1,95 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	InitMeasure(properties.NewProperties())
+	for i := 0; i < 10; i++ {
+		Measure("READ", time.Now(), time.Millisecond)
+	}
+	Measure("READ_ERROR", time.Now(), time.Millisecond)
+	Measure("READ_ERROR_TIMEOUT", time.Now(), time.Millisecond)
+	for i := 0; i < 10; i++ {
+		Measure("TOTAL", time.Now(), time.Millisecond)
+		Measure("READ_INTENDED", time.Now(), 2*time.Millisecond)
+		Measure("TOTAL_INTENDED", time.Now(), 2*time.Millisecond)
+	}
+	Measure("READ_RETRY", time.Now(), time.Millisecond)
+	Measure("TXN", time.Now(), time.Millisecond)
+
+	srv := httptest.NewServer(PrometheusHandler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + PrometheusPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("scrape failed: %s %s", resp.Status, data)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for _, want := range []string{
+		"# TYPE ycsb_operations_total counter",
+		`ycsb_operations_total{operation="READ"} 10`,
+		`ycsb_errors_total{operation="READ"} 1`,
+		`ycsb_classified_errors_total{operation="READ",class="TIMEOUT"} 1`,
+		"# TYPE ycsb_latency_microseconds summary",
+		`ycsb_latency_microseconds{operation="READ",quantile="0.99"} 1000`,
+		`ycsb_latency_microseconds_sum{operation="READ"} 10000`,
+		`ycsb_latency_microseconds_count{operation="READ"} 10`,
+		`ycsb_intended_latency_microseconds{operation="READ",quantile="0.99"} 2000`,
+		`ycsb_intended_latency_microseconds_count{operation="READ"} 10`,
+		`ycsb_events_total{event="READ_RETRY"} 1`,
+		`ycsb_events_total{event="TXN"} 1`,
+		`ycsb_event_latency_microseconds_count{event="TXN"} 1`,
+	} {
+		found := false
+		for _, line := range lines {
+			if line == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("%q is not exported:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), `operation="READ_ERROR`) {
+		t.Fatalf("errors must not be exported as operations:\n%s", data)
+	}
+	// the aggregated and the intended latencies would count the operations twice.
+	// so would the retries and transactions, which are the events.
+	for _, op := range []string{"TOTAL", "READ_INTENDED", "READ_RETRY", "TXN"} {
+		if strings.Contains(string(data), `operation="`+op) {
+			t.Fatalf("%s must not be exported as an operation:\n%s", op, data)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties"
+)
+
+func readCount(t *testing.T) int64 {
+	stats, err := Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stats["READ"]; !ok {
+		return 0
+	}
+	return stats["READ"][COUNT].(int64)
+}
+
+func TestMeasureContext(t *testing.T) {
+	InitMeasure(properties.NewProperties())
+
+	const threads = 8
+	// more than shardSize, so the full shards are flushed too.
+	const ops = 3000
+	var wg sync.WaitGroup
+	wg.Add(threads)
+	for i := 0; i < threads; i++ {
+		go func() {
+			defer wg.Done()
+			ctx := InitThread(context.Background())
+			for j := 0; j < ops; j++ {
+				MeasureContext(ctx, "READ", time.Now(), time.Millisecond)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the shards are drained when the measurement is read.
+	if n := readCount(t); n != threads*ops {
+		t.Fatalf("want %d reads, but got %d", threads*ops, n)
+	}
+	MeasureContext(InitThread(context.Background()), "READ", time.Now(), time.Millisecond)
+	if n := readCount(t); n != threads*ops+1 {
+		t.Fatalf("want %d reads, but got %d", threads*ops+1, n)
+	}
+}
+
+func BenchmarkMeasureContext(b *testing.B) {
+	InitMeasure(properties.NewProperties())
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := InitThread(context.Background())
+		for pb.Next() {
+			MeasureContext(ctx, "READ", time.Now(), time.Millisecond)
+		}
+	})
+}
+
+func BenchmarkMeasure(b *testing.B) {
+	InitMeasure(properties.NewProperties())
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Measure("READ", time.Now(), time.Millisecond)
+		}
+	})
+}
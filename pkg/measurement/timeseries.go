@@ -0,0 +1,181 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+// window is the latencies in us of the operations finished in a time window.
+type window struct {
+	count int64
+	sum   int64
+	min   int64
+	max   int64
+}
+
+func (w *window) add(latency int64) {
+	if w.count == 0 || latency < w.min {
+		w.min = latency
+	}
+	if latency > w.max {
+		w.max = latency
+	}
+	w.count++
+	w.sum += latency
+}
+
+func (w *window) merge(o *window) {
+	if o.count == 0 {
+		return
+	}
+	if w.count == 0 || o.min < w.min {
+		w.min = o.min
+	}
+	if o.max > w.max {
+		w.max = o.max
+	}
+	w.count += o.count
+	w.sum += o.sum
+}
+
+// formatAvg formats the average latency without an exponent.
+func formatAvg(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func (w *window) avg() float64 {
+	if w.count == 0 {
+		return 0
+	}
+	return float64(w.sum) / float64(w.count)
+}
+
+// timeSeries buckets the latencies of every operation into windows of
+// timeseries.granularity ms since the start of the measurement, like the
+// timeseries measurement of Java YCSB.
+type timeSeries struct {
+	startTime   time.Time
+	granularity int64
+
+	// windows are keyed by the start of the window in ms since startTime.
+	windows map[string]map[int64]*window
+	// summarized is the end of the windows in ms already printed by Summary.
+	summarized int64
+}
+
+// InitTimeSeries creates the timeseries measurer.
+func InitTimeSeries(p *properties.Properties) *timeSeries {
+	granularity := p.GetInt64(prop.TimeSeriesGranularity, prop.TimeSeriesGranularityDefault)
+	if granularity <= 0 {
+		panic(fmt.Sprintf("invalid %s: %d", prop.TimeSeriesGranularity, granularity))
+	}
+	return &timeSeries{
+		startTime:   time.Now(),
+		granularity: granularity,
+		windows:     make(map[string]map[int64]*window, 16),
+	}
+}
+
+func (t *timeSeries) GenerateExtendedOutputs() {
+}
+
+// Measure puts the operation into the window in which it finishes.
+func (t *timeSeries) Measure(op string, start time.Time, lan time.Duration) {
+	elapsed := start.Add(lan).Sub(t.startTime).Milliseconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	key := elapsed / t.granularity * t.granularity
+
+	opW, ok := t.windows[op]
+	if !ok {
+		opW = make(map[int64]*window)
+		t.windows[op] = opW
+	}
+	w, ok := opW[key]
+	if !ok {
+		w = new(window)
+		opW[key] = w
+	}
+	w.add(lan.Microseconds())
+}
+
+func (t *timeSeries) ops() []string {
+	ops := make([]string, 0, len(t.windows))
+	for op := range t.windows {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// Summary prints the count, min, max and average latency of every operation
+// in the complete windows since the last summary.
+func (t *timeSeries) Summary() {
+	end := time.Now().Sub(t.startTime).Milliseconds() / t.granularity * t.granularity
+	for _, op := range t.ops() {
+		var total window
+		for key, w := range t.windows[op] {
+			if key >= t.summarized && key < end {
+				total.merge(w)
+			}
+		}
+		fmt.Fprintf(os.Stdout, "[%s: Count=%d, Max=%d, Min=%d, Avg=%.2f]\n", op, total.count, total.max, total.min, total.avg())
+	}
+	t.summarized = end
+}
+
+// Output writes the totals and the average latency of every window in the
+// Java YCSB format, e.g.
+//
+//	[READ], Operations, 1000
+//	[READ], AverageLatency(us), 123.4
+//	[READ], MinLatency(us), 10
+//	[READ], MaxLatency(us), 5000
+//	[READ], 0, 120.5
+//	[READ], 1000, 126.3
+func (t *timeSeries) Output(w io.Writer) error {
+	for _, op := range t.ops() {
+		opW := t.windows[op]
+		keys := make([]int64, 0, len(opW))
+		var total window
+		for key, win := range opW {
+			keys = append(keys, key)
+			total.merge(win)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+		if _, err := fmt.Fprintf(w, "[%s], Operations, %d\n", op, total.count); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "[%s], AverageLatency(us), %s\n", op, formatAvg(total.avg()))
+		fmt.Fprintf(w, "[%s], MinLatency(us), %d\n", op, total.min)
+		fmt.Fprintf(w, "[%s], MaxLatency(us), %d\n", op, total.max)
+		for _, key := range keys {
+			if _, err := fmt.Fprintf(w, "[%s], %d, %s\n", op, key, formatAvg(opW[key].avg())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measurement
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+func TestTimeSeriesOutput(t *testing.T) {
+	p := properties.NewProperties()
+	p.Set(prop.TimeSeriesGranularity, "100")
+	ts := InitTimeSeries(p)
+
+	// the operations finish at 10ms and 20ms, then at 150ms.
+	ts.Measure("READ", ts.startTime, 10*time.Millisecond)
+	ts.Measure("READ", ts.startTime, 20*time.Millisecond)
+	ts.Measure("READ", ts.startTime.Add(100*time.Millisecond), 50*time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := ts.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `[READ], Operations, 3
+[READ], AverageLatency(us), 26666.666666666668
+[READ], MinLatency(us), 10000
+[READ], MaxLatency(us), 50000
+[READ], 0, 15000
+[READ], 100, 50000
+`
+	if buf.String() != want {
+		t.Fatalf("want output\n%s\nbut got\n%s", want, buf.String())
+	}
+}
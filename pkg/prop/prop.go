@@ -18,6 +18,9 @@ const (
 	InsertStart        = "insertstart"
 	InsertCount        = "insertcount"
 	InsertStartDefault = int64(0)
+	// TransactionInsertStart is the first key inserted by the run, recordcount
+	// by default.
+	TransactionInsertStart = "transactioninsertstart"
 
 	OperationCount     = "operationcount"
 	RecordCount        = "recordcount"
@@ -29,6 +32,7 @@ const (
 	ThreadCount        = "threadcount"
 	ThreadCountDefault = int64(200)
 	Target             = "target"
+	TargetSchedule     = "target.schedule"
 	MaxExecutiontime   = "maxexecutiontime"
 	WarmUpTime         = "warmuptime"
 	DoTransactions     = "dotransactions"
@@ -66,7 +70,9 @@ const (
 	ScanProportionDefault            = float64(0.0)
 	ReadModifyWriteProportion        = "readmodifywriteproportion"
 	ReadModifyWriteProportionDefault = float64(0.0)
-	// "uniform", "zipfian", "latest"
+	DeleteProportion                 = "deleteproportion"
+	DeleteProportionDefault          = float64(0.0)
+	// "uniform", "zipfian", "latest", "trace"
 	RequestDistribution        = "requestdistribution"
 	RequestDistributionDefault = "uniform"
 	ZeroPadding                = "zeropadding"
@@ -95,6 +101,12 @@ const (
 	ExponentialFrac              = "exponential.frac"
 	ExponentialFracDefault       = float64(0.8571428571)
 
+	// the keys of the "trace" request distribution, sampled by their
+	// frequencies or issued in order, see generator.NewTraceFromFile.
+	RequestDistributionFile            = "requestdistribution.file"
+	RequestDistributionFileType        = "requestdistribution.file.type"
+	RequestDistributionFileTypeDefault = "frequency"
+
 	DebugPprof        = "debug.pprof"
 	DebugPprofDefault = ":6060"
 
@@ -106,6 +118,10 @@ const (
 	Silence        = "silence"
 	SilenceDefault = true
 
+	// the seed of the random generators of every thread, which are seeded by
+	// the current time if it is not set.
+	RandomSeed = "randomseed"
+
 	KeyPrefix        = "keyprefix"
 	KeyPrefixDefault = "user"
 
@@ -114,6 +130,32 @@ const (
 	MeasurementType          = "measurementtype"
 	MeasurementTypeDefault   = "histogram"
 	MeasurementRawOutputFile = "measurement.output_file"
+	// the raw measurement writes gzip compressed files if set, and starts a new
+	// file every rotatesize MB if it is positive.
+	MeasurementRawGzip        = "measurement.raw.gzip"
+	MeasurementRawGzipDefault = false
+	MeasurementRawRotateSize  = "measurement.raw.rotatesize"
+	// the window of the timeseries measurement in ms
+	TimeSeriesGranularity        = "timeseries.granularity"
+	TimeSeriesGranularityDefault = int64(1000)
+	// "op", "intended", "both"
+	MeasurementLatency        = "measurement.latency"
+	MeasurementLatencyDefault = "op"
+
+	// SearchSLO properties -- related to the max throughput search under a latency SLO
+	SearchSLO               = "search.slo"
+	SearchOperation         = "search.operation"
+	SearchOperationDefault  = "TOTAL"
+	SearchPercentile        = "search.percentile"
+	SearchPercentileDefault = float64(99)
+	SearchTrialTime         = "search.trialtime"
+	SearchTrialTimeDefault  = int64(30)
+	SearchMinTarget         = "search.mintarget"
+	SearchMaxTarget         = "search.maxtarget"
+	SearchPrecision         = "search.precision"
+	SearchPrecisionDefault  = float64(0.05)
+	SearchMaxTrials         = "search.maxtrials"
+	SearchMaxTrialsDefault  = int(10)
 
 	Command = "command"
 
@@ -124,4 +166,91 @@ const (
 	MeasurementHistogramPercentileExportDefault         = false
 	MeasurementHistogramPercentileExportFilepath        = "histogram.percentiles.export.filepath"
 	MeasurementHistogramPercentileExportFilepathDefault = "./"
+
+	// the middlewares around the DB from the outermost, measure by default,
+	// followed by retry if retry.maxattempts is greater than 1.
+	DBMiddleware        = "db.middleware"
+	DBMiddlewareDefault = "measure"
+	// the ops/sec limit of the ratelimit middleware
+	RateLimitOps = "ratelimit.ops"
+
+	// Faulty properties -- related to the faulty middleware, the faults are
+	// configured by faulty.<fault> or faulty.<op>.<fault>, where the fault is
+	// one of errorrate, latencyrate, latency, timeoutrate, timeout, hangrate
+	// and batchfailrate.
+	FaultyErrorClass        = "faulty.errorclass"
+	FaultyErrorClassDefault = "OTHER"
+	FaultySchedule          = "faulty.schedule"
+
+	// the trace file written by the record middleware, and read by the replay
+	// workload, which issues the operations at their recorded time if
+	// replay.timing is set.
+	RecordFile          = "record.file"
+	ReplayFile          = "replay.file"
+	ReplayTiming        = "replay.timing"
+	ReplayTimingDefault = false
+
+	// Retry properties -- related to the retries of the failed operations
+	RetryMaxAttempts            = "retry.maxattempts"
+	RetryMaxAttemptsDefault     = 1
+	RetryInitialInterval        = "retry.initialinterval"
+	RetryInitialIntervalDefault = int64(10)
+	RetryMaxInterval            = "retry.maxinterval"
+	RetryMaxIntervalDefault     = int64(1000)
+	RetryMultiplier             = "retry.multiplier"
+	RetryMultiplierDefault      = float64(2)
+	RetryJitter                 = "retry.jitter"
+	RetryJitterDefault          = float64(0.5)
+	RetryClasses                = "retry.classes"
+	RetryClassesDefault         = "TIMEOUT,CONFLICT,THROTTLED"
+
+	// ClosedEconomy properties -- related to the closedeconomy workload, whose
+	// transactions move money between closedeconomy.txnsize accounts.
+	ClosedEconomyInitialBalance        = "closedeconomy.initialbalance"
+	ClosedEconomyInitialBalanceDefault = int64(1000)
+	ClosedEconomyTxnSize               = "closedeconomy.txnsize"
+	ClosedEconomyTxnSizeDefault        = int64(2)
+
+	// TimeSeriesWorkload properties -- related to the timeseries workload,
+	// whose series are the metrics with every combination of the tag values.
+	MetricCount              = "metriccount"
+	MetricCountDefault       = int64(1)
+	TagCardinality           = "tagcardinality"
+	TagCardinalityDefault    = "1,2,4,8"
+	TimestampKey             = "timestampkey"
+	TimestampKeyDefault      = "YCSBTS"
+	ValueKey                 = "valuekey"
+	ValueKeyDefault          = "YCSBV"
+	TimestampStart           = "timestampstart"
+	TimestampStartDefault    = int64(1577836800)
+	TimestampInterval        = "timestampinterval"
+	TimestampIntervalDefault = int64(60)
+	QueryTimeSpan            = "querytimespan"
+	QueryTimeSpanDefault     = int64(10)
+	GroupByFunction          = "groupbyfunction"
+	ValueDistribution        = "valuedistribution"
+	ValueDistributionDefault = "uniform"
+	MaxValue                 = "maxvalue"
+	MaxValueDefault          = int64(10000)
+
+	// Consistency properties -- related to the consistency workload, whose
+	// recorded history can be checked again by the check command.
+	ConsistencyHistory = "consistency.history"
+
+	// Query properties -- related to the query workload, whose predicates on
+	// the query.fields are served by their secondary indexes.
+	QueryFields             = "query.fields"
+	QueryFieldsDefault      = "field1"
+	QueryCardinality        = "query.cardinality"
+	QueryCardinalityDefault = int64(1000)
+	QueryRangeLength        = "query.rangelength"
+	QueryRangeLengthDefault = int64(10)
+	QueryLimit              = "query.limit"
+	QueryLimitDefault       = int64(100)
+
+	// HdrHistogramFileOutput properties -- related to the HdrHistogram interval logs
+	HdrHistogramFileOutput        = "hdrhistogram.fileoutput"
+	HdrHistogramFileOutputDefault = false
+	HdrHistogramOutputPath        = "hdrhistogram.output.path"
+	HdrHistogramOutputPathDefault = "./"
 )
@@ -18,6 +18,10 @@ import (
 	"math/rand"
 	"os"
 	"sync"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
 )
 
 // Fatalf prints the message and exits the program.
@@ -43,6 +47,19 @@ func RandBytes(r *rand.Rand, b []byte) {
 	}
 }
 
+// NewThreadRand returns the random generator of a thread for the user, e.g.
+// "core" or "basic". If randomseed is set, the seed is derived from it, the
+// user and the thread, so every run generates the same sequences, otherwise
+// it is seeded by the current time.
+func NewThreadRand(p *properties.Properties, user string, threadID int) *rand.Rand {
+	if _, ok := p.Get(prop.RandomSeed); !ok {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	seed := Hash64(p.GetInt64(prop.RandomSeed, 0)) ^ StringHash64(user)
+	return rand.New(rand.NewSource(Hash64(seed + int64(threadID))))
+}
+
 // BufPool is a bytes.Buffer pool
 type BufPool struct {
 	p *sync.Pool
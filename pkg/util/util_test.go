@@ -0,0 +1,51 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+func TestNewThreadRand(t *testing.T) {
+	p := properties.NewProperties()
+	p.Set(prop.RandomSeed, "42")
+
+	sequence := func(user string, threadID int) [4]int64 {
+		r := NewThreadRand(p, user, threadID)
+		var seq [4]int64
+		for i := range seq {
+			seq[i] = r.Int63()
+		}
+		return seq
+	}
+
+	if sequence("core", 1) != sequence("core", 1) {
+		t.Fatal("the same seed and thread must generate the same sequence")
+	}
+	if sequence("core", 1) == sequence("core", 2) {
+		t.Fatal("the threads must generate different sequences")
+	}
+	if sequence("core", 1) == sequence("basic", 1) {
+		t.Fatal("the users must generate different sequences")
+	}
+
+	seq := sequence("core", 1)
+	p.Set(prop.RandomSeed, "43")
+	if sequence("core", 1) == seq {
+		t.Fatal("the seeds must generate different sequences")
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/generator"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+const closedEconomyStateKey = contextKey("closedeconomy")
+
+// balanceField is the field holding the balance of an account.
+const balanceField = "field0"
+
+type closedEconomyState struct {
+	r *rand.Rand
+}
+
+// closedEconomy is the closed economy workload of YCSB+T. Every record is an
+// account loaded with closedeconomy.initialbalance, and every transaction
+// reads closedeconomy.txnsize accounts, then moves random amounts between
+// them unless it is read-only, so the total balance never changes. Validate
+// checks the total after the run, a different total is an anomaly of the
+// transactions of the DB.
+type closedEconomy struct {
+	p *properties.Properties
+
+	table          string
+	insertStart    int64
+	recordCount    int64
+	initialBalance int64
+	txnSize        int
+	orderedInserts bool
+	zeroPadding    int64
+
+	keySequence      ycsb.Generator
+	keyChooser       ycsb.Generator
+	operationChooser *generator.Discrete
+
+	commits    int64
+	aborts     int64
+	noTxnsOnce sync.Once
+}
+
+// Load implements the Workload Load interface.
+func (c *closedEconomy) Load(ctx context.Context, db ycsb.DB, totalCount int64) error {
+	return nil
+}
+
+// InitThread implements the Workload InitThread interface.
+func (c *closedEconomy) InitThread(ctx context.Context, threadID int, _ int) context.Context {
+	state := &closedEconomyState{r: util.NewThreadRand(c.p, "closedeconomy", threadID)}
+	return context.WithValue(ctx, closedEconomyStateKey, state)
+}
+
+// CleanupThread implements the Workload CleanupThread interface.
+func (c *closedEconomy) CleanupThread(_ context.Context) {
+}
+
+// Close implements the Workload Close interface.
+func (c *closedEconomy) Close() error {
+	return nil
+}
+
+func (c *closedEconomy) buildKeyName(keyNum int64) string {
+	if !c.orderedInserts {
+		keyNum = util.Hash64(keyNum)
+	}
+
+	prefix := c.p.GetString(prop.KeyPrefix, prop.KeyPrefixDefault)
+	return fmt.Sprintf("%s%0[3]*[2]d", prefix, keyNum, c.zeroPadding)
+}
+
+func (c *closedEconomy) buildBalance(balance int64) map[string][]byte {
+	return map[string][]byte{balanceField: []byte(strconv.FormatInt(balance, 10))}
+}
+
+func parseBalance(key string, values map[string][]byte) (int64, error) {
+	value, ok := values[balanceField]
+	if !ok {
+		return 0, fmt.Errorf("account %s is not found", key)
+	}
+
+	balance, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid balance %q of account %s", value, key)
+	}
+	return balance, nil
+}
+
+// DoInsert implements the Workload DoInsert interface.
+func (c *closedEconomy) DoInsert(ctx context.Context, db ycsb.DB) error {
+	state := ctx.Value(closedEconomyStateKey).(*closedEconomyState)
+	key := c.buildKeyName(c.keySequence.Next(state.r))
+	return db.Insert(ctx, c.table, key, c.buildBalance(c.initialBalance))
+}
+
+// DoBatchInsert implements the Workload DoBatchInsert interface.
+func (c *closedEconomy) DoBatchInsert(ctx context.Context, batchSize int, db ycsb.DB) error {
+	batchDB, ok := db.(ycsb.BatchDB)
+	if !ok {
+		return fmt.Errorf("the %T doesn't implement the batchDB interface", db)
+	}
+	state := ctx.Value(closedEconomyStateKey).(*closedEconomyState)
+
+	keys := make([]string, batchSize)
+	values := make([]map[string][]byte, batchSize)
+	for i := 0; i < batchSize; i++ {
+		keys[i] = c.buildKeyName(c.keySequence.Next(state.r))
+		values[i] = c.buildBalance(c.initialBalance)
+	}
+	return batchDB.BatchInsert(ctx, c.table, keys, values)
+}
+
+// chooseAccounts chooses txnSize distinct accounts.
+func (c *closedEconomy) chooseAccounts(state *closedEconomyState) []string {
+	keyNums := make(map[int64]struct{}, c.txnSize)
+	keys := make([]string, 0, c.txnSize)
+	for len(keys) < c.txnSize {
+		keyNum := c.keyChooser.Next(state.r)
+		if _, ok := keyNums[keyNum]; ok {
+			continue
+		}
+		keyNums[keyNum] = struct{}{}
+		keys = append(keys, c.buildKeyName(keyNum))
+	}
+	return keys
+}
+
+// transfer reads the balances of the accounts, and moves a random part of
+// every balance to the next account unless readOnly is set.
+func (c *closedEconomy) transfer(ctx context.Context, db ycsb.DB, state *closedEconomyState, keys []string, readOnly bool) error {
+	balances := make([]int64, len(keys))
+	for i, key := range keys {
+		values, err := db.Read(ctx, c.table, key, []string{balanceField})
+		if err != nil {
+			return err
+		}
+		if balances[i], err = parseBalance(key, values); err != nil {
+			return err
+		}
+	}
+	if readOnly {
+		return nil
+	}
+
+	for i := 0; i < len(balances)-1; i++ {
+		if balances[i] <= 0 {
+			continue
+		}
+		amount := state.r.Int63n(balances[i] + 1)
+		balances[i] -= amount
+		balances[i+1] += amount
+	}
+	for i, key := range keys {
+		if err := db.Update(ctx, c.table, key, c.buildBalance(balances[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DoTransaction implements the Workload DoTransaction interface, it runs
+// one transfer or read-only transaction, which is measured as TXN if it is
+// committed and TXN_ABORT if it is aborted.
+func (c *closedEconomy) DoTransaction(ctx context.Context, db ycsb.DB) (err error) {
+	state := ctx.Value(closedEconomyStateKey).(*closedEconomyState)
+	keys := c.chooseAccounts(state)
+	readOnly := operationType(c.operationChooser.Next(state.r)) == read
+
+	start := time.Now()
+	defer func() {
+		// don't report the transaction interrupted by the stopping run
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			atomic.AddInt64(&c.aborts, 1)
+			measurement.MeasureContext(ctx, "TXN_ABORT", start, time.Now().Sub(start))
+			return
+		}
+		atomic.AddInt64(&c.commits, 1)
+		measurement.MeasureContext(ctx, "TXN", start, time.Now().Sub(start))
+	}()
+
+	txnDB, ok := db.(ycsb.TransactionalDB)
+	txnCtx := ctx
+	if ok {
+		txnCtx, err = txnDB.Begin(ctx)
+		if err == ycsb.ErrNotTransactional {
+			ok = false
+			txnCtx = ctx
+		} else if err != nil {
+			return err
+		}
+	}
+	if !ok {
+		c.noTxnsOnce.Do(func() {
+			fmt.Println("the DB doesn't support transactions, the operations run without them")
+		})
+		return c.transfer(ctx, db, state, keys, readOnly)
+	}
+
+	if err = c.transfer(txnCtx, db, state, keys, readOnly); err != nil {
+		if abortErr := txnDB.Abort(txnCtx); abortErr != nil {
+			return fmt.Errorf("%w, and abort failed %v", err, abortErr)
+		}
+		return err
+	}
+	return txnDB.Commit(txnCtx)
+}
+
+// DoBatchTransaction implements the Workload DoBatchTransaction interface,
+// the transaction is already a batch of operations.
+func (c *closedEconomy) DoBatchTransaction(ctx context.Context, batchSize int, db ycsb.DB) error {
+	return c.DoTransaction(ctx, db)
+}
+
+// Validate implements the Validator Validate interface, it reads all the
+// accounts and checks the total balance is unchanged.
+func (c *closedEconomy) Validate(ctx context.Context, db ycsb.DB) error {
+	var total, missing, negative int64
+	for keyNum := c.insertStart; keyNum < c.insertStart+c.recordCount; keyNum++ {
+		key := c.buildKeyName(keyNum)
+		values, err := db.Read(ctx, c.table, key, []string{balanceField})
+		if err != nil {
+			return err
+		}
+		balance, err := parseBalance(key, values)
+		if err != nil {
+			missing++
+			continue
+		}
+		if balance < 0 {
+			negative++
+		}
+		total += balance
+	}
+
+	expected := c.recordCount * c.initialBalance
+	commits := atomic.LoadInt64(&c.commits)
+	// the anomaly score of YCSB+T, the drift of the total per transaction.
+	var score float64
+	if drift := total - expected; commits > 0 && drift != 0 {
+		if drift < 0 {
+			drift = -drift
+		}
+		score = float64(drift) / float64(commits)
+	}
+	fmt.Printf("Closed economy: %d committed, %d aborted transactions, total balance %d, expected %d, anomaly score %f\n",
+		commits, atomic.LoadInt64(&c.aborts), total, expected, score)
+
+	if missing > 0 || negative > 0 || total != expected {
+		return fmt.Errorf("the total balance is %d but expected %d, %d accounts are missing and %d accounts are negative",
+			total, expected, missing, negative)
+	}
+	return nil
+}
+
+type closedEconomyCreator struct {
+}
+
+// Create implements the WorkloadCreator Create interface.
+func (closedEconomyCreator) Create(p *properties.Properties) (ycsb.Workload, error) {
+	c := &closedEconomy{
+		p:              p,
+		table:          p.GetString(prop.TableName, prop.TableNameDefault),
+		insertStart:    p.GetInt64(prop.InsertStart, prop.InsertStartDefault),
+		recordCount:    p.GetInt64(prop.RecordCount, prop.RecordCountDefault),
+		initialBalance: p.GetInt64(prop.ClosedEconomyInitialBalance, prop.ClosedEconomyInitialBalanceDefault),
+		txnSize:        int(p.GetInt64(prop.ClosedEconomyTxnSize, prop.ClosedEconomyTxnSizeDefault)),
+		orderedInserts: p.GetString(prop.InsertOrder, prop.InsertOrderDefault) != "hashed",
+		zeroPadding:    p.GetInt64(prop.ZeroPadding, prop.ZeroPaddingDefault),
+	}
+	if c.recordCount <= 0 {
+		return nil, fmt.Errorf("%s must be positive for the accounts", prop.RecordCount)
+	}
+	if c.txnSize < 1 || int64(c.txnSize) > c.recordCount {
+		return nil, fmt.Errorf("%s must be in [1, %d], but got %d", prop.ClosedEconomyTxnSize, c.recordCount, c.txnSize)
+	}
+
+	// the accounts are loaded from insertstart.
+	c.keySequence = generator.NewCounter(c.insertStart)
+	lastKeyNum := c.insertStart + c.recordCount - 1
+	requestDistrib := p.GetString(prop.RequestDistribution, prop.RequestDistributionDefault)
+	switch requestDistrib {
+	case "uniform":
+		c.keyChooser = generator.NewUniform(c.insertStart, lastKeyNum)
+	case "zipfian":
+		c.keyChooser = generator.NewScrambledZipfian(c.insertStart, lastKeyNum, generator.ZipfianConstant)
+	default:
+		return nil, fmt.Errorf("unsupported request distribution %s", requestDistrib)
+	}
+
+	// the reads are the read-only transactions and the updates the transfers.
+	readProportion := p.GetFloat64(prop.ReadProportion, prop.ReadProportionDefault)
+	updateProportion := p.GetFloat64(prop.UpdateProportion, prop.UpdateProportionDefault)
+	if readProportion <= 0 && updateProportion <= 0 {
+		return nil, fmt.Errorf("%s or %s must be positive", prop.ReadProportion, prop.UpdateProportion)
+	}
+	c.operationChooser = generator.NewDiscrete()
+	if readProportion > 0 {
+		c.operationChooser.Add(readProportion, int64(read))
+	}
+	if updateProportion > 0 {
+		c.operationChooser.Add(updateProportion, int64(update))
+	}
+
+	return c, nil
+}
+
+func init() {
+	ycsb.RegisterWorkloadCreator("closedeconomy", closedEconomyCreator{})
+	ycsb.RegisterWorkloadCreator("site.ycsb.workloads.ClosedEconomyWorkload", closedEconomyCreator{})
+}
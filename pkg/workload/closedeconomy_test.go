@@ -0,0 +1,105 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+type txnKey struct{}
+
+// txnDB applies the writes of a transaction on commit, and fails every
+// failCommit-th commit.
+type txnDB struct {
+	*memDB
+	failCommit int
+	commits    int
+}
+
+func (db *txnDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
+	if writes, ok := ctx.Value(txnKey{}).(map[string]map[string][]byte); ok {
+		if row, ok := writes[key]; ok {
+			return row, nil
+		}
+	}
+	return db.memDB.Read(ctx, table, key, fields)
+}
+
+func (db *txnDB) Update(ctx context.Context, table string, key string, values map[string][]byte) error {
+	if writes, ok := ctx.Value(txnKey{}).(map[string]map[string][]byte); ok {
+		writes[key] = values
+		return nil
+	}
+	return db.memDB.Update(ctx, table, key, values)
+}
+
+func (db *txnDB) Begin(ctx context.Context) (context.Context, error) {
+	return context.WithValue(ctx, txnKey{}, make(map[string]map[string][]byte)), nil
+}
+
+func (db *txnDB) Commit(ctx context.Context) error {
+	db.commits++
+	if db.failCommit > 0 && db.commits%db.failCommit == 0 {
+		return errors.New("write conflict")
+	}
+	for key, values := range ctx.Value(txnKey{}).(map[string]map[string][]byte) {
+		db.memDB.Update(ctx, "", key, values)
+	}
+	return nil
+}
+
+func (db *txnDB) Abort(ctx context.Context) error {
+	return nil
+}
+
+func TestClosedEconomy(t *testing.T) {
+	p := properties.NewProperties()
+	p.Set(prop.RecordCount, "10")
+	p.Set(prop.InsertStart, "100")
+	p.Set(prop.ReadProportion, "0.2")
+	p.Set(prop.UpdateProportion, "0.8")
+	p.Set(prop.ClosedEconomyTxnSize, "3")
+	p.Set(prop.RandomSeed, "1")
+
+	w, ctx := newTestWorkload(t, closedEconomyCreator{}, p)
+	db := &txnDB{memDB: newMemDB(), failCommit: 3}
+	doInserts(t, w, ctx, db, 10)
+
+	aborts := 0
+	for i := 0; i < 300; i++ {
+		if err := w.DoTransaction(ctx, db); err != nil {
+			aborts++
+		}
+	}
+	if aborts != 100 {
+		t.Fatalf("want every third transaction aborted, but got %d aborts", aborts)
+	}
+
+	validator := w.(ycsb.Validator)
+	if err := validator.Validate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	// a lost update breaks the invariant.
+	db.rows[w.(*closedEconomy).buildKeyName(100)] = map[string][]byte{balanceField: []byte("0")}
+	if err := validator.Validate(ctx, db); err == nil {
+		t.Fatal("the changed total balance should fail the validation")
+	}
+}
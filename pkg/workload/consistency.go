@@ -0,0 +1,612 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/generator"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+const consistencyStateKey = contextKey("consistency")
+
+// versionField is the field holding the versioned value of a key.
+const versionField = "field0"
+
+// The anomalies found by the consistency workload, which are measured as the
+// operations of the same names.
+const (
+	// AnomalyReadYourWrites is a read older than a write of the same thread.
+	AnomalyReadYourWrites = "READ_YOUR_WRITES_VIOLATION"
+	// AnomalyMonotonicRead is a read older than a previous read of the same
+	// thread.
+	AnomalyMonotonicRead = "MONOTONIC_READ_VIOLATION"
+	// AnomalyStaleRead is a read older than a write acknowledged before the
+	// read starts.
+	AnomalyStaleRead = "STALE_READ"
+	// AnomalyLostUpdate is a final value older than the highest acknowledged
+	// write.
+	AnomalyLostUpdate = "LOST_UPDATE"
+)
+
+type consistencyState struct {
+	r        *rand.Rand
+	threadID int
+	seq      int64
+	// own is the last version of every key written by the thread.
+	own map[int64]int64
+}
+
+// consistencyEvent is an operation of the history, a write, a read, or a
+// final read of Validate.
+type consistencyEvent struct {
+	// Time is the time since the start in us.
+	Time int64 `json:"t"`
+	// Latency is the latency of the operation in us, a write is acknowledged
+	// at Time+Latency.
+	Latency int64  `json:"latency"`
+	Thread  int    `json:"thread"`
+	Op      string `json:"op"`
+	Key     string `json:"key"`
+	Version int64  `json:"version"`
+	// Acked is the highest acknowledged version of the key when the read
+	// starts.
+	Acked int64 `json:"acked,omitempty"`
+	// Own is the last version of the key written by the thread when the read
+	// starts.
+	Own int64  `json:"own,omitempty"`
+	Err string `json:"err,omitempty"`
+}
+
+// ConsistencyReport is the result of the consistency checks.
+type ConsistencyReport struct {
+	Reads     int64
+	Writes    int64
+	Anomalies map[string]int64
+}
+
+// AnomalyCount returns the number of all the anomalies.
+func (r *ConsistencyReport) AnomalyCount() int64 {
+	var n int64
+	for _, count := range r.Anomalies {
+		n += count
+	}
+	return n
+}
+
+func (r *ConsistencyReport) String() string {
+	names := make([]string, 0, len(r.Anomalies))
+	for name := range r.Anomalies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d reads, %d writes, %d anomalies", r.Reads, r.Writes, r.AnomalyCount())
+	for _, name := range names {
+		fmt.Fprintf(&b, ", %s: %d", name, r.Anomalies[name])
+	}
+	return b.String()
+}
+
+// consistencyChecker checks the events of a history in their order.
+type consistencyChecker struct {
+	mu sync.Mutex
+	// lastRead is the last version of every key read by every thread.
+	lastRead map[int]map[string]int64
+	// failed are the versions of every key whose writes failed. A failed
+	// write may still be applied at any time later, even after the writes
+	// of the higher versions, so the reads of these versions are not checked.
+	failed map[string]map[int64]bool
+	report ConsistencyReport
+}
+
+func newConsistencyChecker() *consistencyChecker {
+	return &consistencyChecker{
+		lastRead: make(map[int]map[string]int64),
+		failed:   make(map[string]map[int64]bool),
+		report:   ConsistencyReport{Anomalies: make(map[string]int64)},
+	}
+}
+
+// check checks the event, and returns the anomaly of it or "" if there is
+// none.
+func (c *consistencyChecker) check(e *consistencyEvent) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e.Err != "" {
+		if e.Op == "WRITE" {
+			failed, ok := c.failed[e.Key]
+			if !ok {
+				failed = make(map[int64]bool)
+				c.failed[e.Key] = failed
+			}
+			failed[e.Version] = true
+		}
+		return ""
+	}
+
+	var anomaly string
+	switch e.Op {
+	case "WRITE":
+		c.report.Writes++
+	case "READ":
+		c.report.Reads++
+		if c.failed[e.Key][e.Version] {
+			break
+		}
+		reads, ok := c.lastRead[e.Thread]
+		if !ok {
+			reads = make(map[string]int64)
+			c.lastRead[e.Thread] = reads
+		}
+
+		switch {
+		case e.Version < e.Own:
+			anomaly = AnomalyReadYourWrites
+		case e.Version < reads[e.Key]:
+			anomaly = AnomalyMonotonicRead
+		case e.Version < e.Acked:
+			anomaly = AnomalyStaleRead
+		}
+		if e.Version > reads[e.Key] {
+			reads[e.Key] = e.Version
+		}
+	case "FINAL":
+		if e.Version < e.Acked && !c.failed[e.Key][e.Version] {
+			anomaly = AnomalyLostUpdate
+		}
+	}
+
+	if anomaly != "" {
+		c.report.Anomalies[anomaly]++
+	}
+	return anomaly
+}
+
+func (c *consistencyChecker) result() ConsistencyReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := ConsistencyReport{
+		Reads:     c.report.Reads,
+		Writes:    c.report.Writes,
+		Anomalies: make(map[string]int64, len(c.report.Anomalies)),
+	}
+	for name, count := range c.report.Anomalies {
+		report.Anomalies[name] = count
+	}
+	return report
+}
+
+// ackedWrite is a write acknowledged at done.
+type ackedWrite struct {
+	done    int64
+	version int64
+}
+
+// historyAcks derives the acknowledged versions of the keys from the WRITE
+// events of a history, which is in the order of the completions, so the
+// writes acknowledged before a read starts are all before the read.
+type historyAcks struct {
+	// acks are the acknowledged writes of every key in the order of done,
+	// without the ones of a higher version acknowledged before, so the
+	// versions are in order too.
+	acks map[string][]ackedWrite
+	// own is the last version of every key written by every thread.
+	own map[int]map[string]int64
+}
+
+func (h *historyAcks) write(e *consistencyEvent) {
+	if e.Err != "" {
+		return
+	}
+
+	own, ok := h.own[e.Thread]
+	if !ok {
+		own = make(map[string]int64)
+		h.own[e.Thread] = own
+	}
+	own[e.Key] = e.Version
+
+	w := ackedWrite{done: e.Time + e.Latency, version: e.Version}
+	acks := h.acks[e.Key]
+	i := sort.Search(len(acks), func(i int) bool { return acks[i].done > w.done })
+	if i > 0 && acks[i-1].version >= w.version {
+		return
+	}
+	// drop the later acknowledged writes of the lower versions.
+	j := i
+	for j < len(acks) && acks[j].version <= w.version {
+		j++
+	}
+	acks = append(acks[:i], append([]ackedWrite{w}, acks[j:]...)...)
+	h.acks[e.Key] = acks
+}
+
+// acked returns the highest version of the key acknowledged at t.
+func (h *historyAcks) acked(key string, t int64) int64 {
+	acks := h.acks[key]
+	i := sort.Search(len(acks), func(i int) bool { return acks[i].done > t })
+	if i == 0 {
+		return 0
+	}
+	return acks[i-1].version
+}
+
+// highest returns the highest acknowledged version of the key.
+func (h *historyAcks) highest(key string) int64 {
+	acks := h.acks[key]
+	if len(acks) == 0 {
+		return 0
+	}
+	return acks[len(acks)-1].version
+}
+
+// CheckHistory checks the history recorded by the consistency workload to
+// consistency.history, e.g. of a run with the online checks of another
+// version. The acknowledged and own versions of the reads are derived from
+// the WRITE events of the history, not taken from the recorded ones.
+func CheckHistory(r io.Reader) (ConsistencyReport, error) {
+	c := newConsistencyChecker()
+	h := &historyAcks{
+		acks: make(map[string][]ackedWrite),
+		own:  make(map[int]map[string]int64),
+	}
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		e := new(consistencyEvent)
+		if err := dec.Decode(e); err == io.EOF {
+			break
+		} else if err != nil {
+			return ConsistencyReport{}, fmt.Errorf("read history failed %v", err)
+		}
+
+		switch e.Op {
+		case "WRITE":
+			h.write(e)
+		case "READ":
+			e.Acked = h.acked(e.Key, e.Time)
+			e.Own = h.own[e.Thread][e.Key]
+		case "FINAL":
+			e.Acked = h.highest(e.Key)
+		}
+		c.check(e)
+	}
+	return c.result(), nil
+}
+
+// consistency writes versioned values and checks the reads for the
+// consistency anomalies. Every value holds the run, the version of the key,
+// the writer thread and its sequence number. The writes of a key are
+// serialized by the lock of the key from the version allocation to the
+// acknowledgement, so the versions of a key are applied in order, and a read
+// of a lower version than expected is an anomaly, see consistencyChecker.
+// Validate reads all the keys after the run, and a final value older than
+// the highest acknowledged version is a lost update.
+//
+// The checks are online, and the events are recorded to consistency.history
+// if it is set, which can be checked again by CheckHistory.
+type consistency struct {
+	p *properties.Properties
+
+	table          string
+	recordCount    int64
+	orderedInserts bool
+	zeroPadding    int64
+	run            string
+	start          time.Time
+
+	keySequence      ycsb.Generator
+	keyChooser       ycsb.Generator
+	operationChooser *generator.Discrete
+
+	// writeMu, attempted and acked are the write lock, the last allocated
+	// version and the highest acknowledged version of every key from
+	// insertStart, attempted is guarded by the write lock.
+	insertStart int64
+	writeMu     []sync.Mutex
+	attempted   []int64
+	acked       []int64
+
+	checker *consistencyChecker
+
+	historyMu sync.Mutex
+	f         *os.File
+	w         *bufio.Writer
+	enc       *json.Encoder
+}
+
+// Load implements the Workload Load interface.
+func (c *consistency) Load(ctx context.Context, db ycsb.DB, totalCount int64) error {
+	return nil
+}
+
+// InitThread implements the Workload InitThread interface.
+func (c *consistency) InitThread(ctx context.Context, threadID int, _ int) context.Context {
+	state := &consistencyState{
+		r:        util.NewThreadRand(c.p, "consistency", threadID),
+		threadID: threadID,
+		own:      make(map[int64]int64),
+	}
+	return context.WithValue(ctx, consistencyStateKey, state)
+}
+
+// CleanupThread implements the Workload CleanupThread interface.
+func (c *consistency) CleanupThread(_ context.Context) {
+}
+
+// Close implements the Workload Close interface.
+func (c *consistency) Close() error {
+	if c.f == nil {
+		return nil
+	}
+
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+	if err := c.w.Flush(); err != nil {
+		c.f.Close()
+		return err
+	}
+	return c.f.Close()
+}
+
+func (c *consistency) buildKeyName(keyNum int64) string {
+	if !c.orderedInserts {
+		keyNum = util.Hash64(keyNum)
+	}
+
+	prefix := c.p.GetString(prop.KeyPrefix, prop.KeyPrefixDefault)
+	return fmt.Sprintf("%s%0[3]*[2]d", prefix, keyNum, c.zeroPadding)
+}
+
+func (c *consistency) buildValue(version int64, writer string, seq int64) map[string][]byte {
+	value := fmt.Sprintf("%s:%d:%s:%d", c.run, version, writer, seq)
+	return map[string][]byte{versionField: []byte(value)}
+}
+
+// parseVersion returns the version of the value, which is 0 if it is not
+// written by this run.
+func (c *consistency) parseVersion(values map[string][]byte) int64 {
+	parts := strings.Split(string(values[versionField]), ":")
+	if len(parts) != 4 || parts[0] != c.run {
+		return 0
+	}
+	version, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// record checks the event, measures its anomaly and records it to the
+// history.
+func (c *consistency) record(ctx context.Context, e *consistencyEvent, start time.Time) {
+	e.Time = start.Sub(c.start).Microseconds()
+	e.Latency = time.Since(start).Microseconds()
+	if anomaly := c.checker.check(e); anomaly != "" {
+		measurement.MeasureContext(ctx, anomaly, start, time.Now().Sub(start))
+	}
+
+	if c.enc == nil {
+		return
+	}
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+	c.enc.Encode(e)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// DoInsert implements the Workload DoInsert interface, the loaded values are
+// of version 0 for the runs.
+func (c *consistency) DoInsert(ctx context.Context, db ycsb.DB) error {
+	state := ctx.Value(consistencyStateKey).(*consistencyState)
+	keyNum := c.keySequence.Next(state.r)
+	return db.Insert(ctx, c.table, c.buildKeyName(keyNum), c.buildValue(0, "load", keyNum))
+}
+
+// DoBatchInsert implements the Workload DoBatchInsert interface.
+func (c *consistency) DoBatchInsert(ctx context.Context, batchSize int, db ycsb.DB) error {
+	batchDB, ok := db.(ycsb.BatchDB)
+	if !ok {
+		return fmt.Errorf("the %T doesn't implement the batchDB interface", db)
+	}
+	state := ctx.Value(consistencyStateKey).(*consistencyState)
+
+	keys := make([]string, batchSize)
+	values := make([]map[string][]byte, batchSize)
+	for i := 0; i < batchSize; i++ {
+		keyNum := c.keySequence.Next(state.r)
+		keys[i] = c.buildKeyName(keyNum)
+		values[i] = c.buildValue(0, "load", keyNum)
+	}
+	return batchDB.BatchInsert(ctx, c.table, keys, values)
+}
+
+// DoTransaction implements the Workload DoTransaction interface.
+func (c *consistency) DoTransaction(ctx context.Context, db ycsb.DB) error {
+	state := ctx.Value(consistencyStateKey).(*consistencyState)
+	keyNum := c.keyChooser.Next(state.r)
+
+	if operationType(c.operationChooser.Next(state.r)) == update {
+		return c.doWrite(ctx, db, state, keyNum)
+	}
+	return c.doRead(ctx, db, state, keyNum)
+}
+
+// DoBatchTransaction implements the Workload DoBatchTransaction interface,
+// the operations of the batch run one by one.
+func (c *consistency) DoBatchTransaction(ctx context.Context, batchSize int, db ycsb.DB) error {
+	for i := 0; i < batchSize; i++ {
+		if err := c.DoTransaction(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *consistency) doWrite(ctx context.Context, db ycsb.DB, state *consistencyState, keyNum int64) error {
+	i := keyNum - c.insertStart
+	c.writeMu[i].Lock()
+	defer c.writeMu[i].Unlock()
+
+	// a failed write may still be applied, so its version is not reused.
+	c.attempted[i]++
+	version := c.attempted[i]
+	state.seq++
+
+	key := c.buildKeyName(keyNum)
+	start := time.Now()
+	err := db.Update(ctx, c.table, key, c.buildValue(version, strconv.Itoa(state.threadID), state.seq))
+	if err == nil {
+		atomic.StoreInt64(&c.acked[i], version)
+		state.own[keyNum] = version
+	}
+	c.record(ctx, &consistencyEvent{Thread: state.threadID, Op: "WRITE", Key: key, Version: version, Err: errString(err)}, start)
+	return err
+}
+
+func (c *consistency) doRead(ctx context.Context, db ycsb.DB, state *consistencyState, keyNum int64) error {
+	key := c.buildKeyName(keyNum)
+	acked := atomic.LoadInt64(&c.acked[keyNum-c.insertStart])
+	start := time.Now()
+	values, err := db.Read(ctx, c.table, key, []string{versionField})
+
+	c.record(ctx, &consistencyEvent{
+		Thread:  state.threadID,
+		Op:      "READ",
+		Key:     key,
+		Version: c.parseVersion(values),
+		Acked:   acked,
+		Own:     state.own[keyNum],
+		Err:     errString(err),
+	}, start)
+	return err
+}
+
+// Validate implements the Validator Validate interface, it reads all the keys
+// for the lost updates, and reports the anomalies of the run.
+func (c *consistency) Validate(ctx context.Context, db ycsb.DB) error {
+	for keyNum := c.insertStart; keyNum < c.insertStart+c.recordCount; keyNum++ {
+		key := c.buildKeyName(keyNum)
+		start := time.Now()
+		values, err := db.Read(ctx, c.table, key, []string{versionField})
+		if err != nil {
+			return err
+		}
+		c.record(ctx, &consistencyEvent{
+			Thread:  -1,
+			Op:      "FINAL",
+			Key:     key,
+			Version: c.parseVersion(values),
+			Acked:   atomic.LoadInt64(&c.acked[keyNum-c.insertStart]),
+		}, start)
+	}
+
+	report := c.checker.result()
+	fmt.Printf("Consistency: %s\n", &report)
+	if n := report.AnomalyCount(); n > 0 {
+		return fmt.Errorf("%d consistency anomalies are found", n)
+	}
+	return nil
+}
+
+type consistencyCreator struct {
+}
+
+// Create implements the WorkloadCreator Create interface.
+func (consistencyCreator) Create(p *properties.Properties) (ycsb.Workload, error) {
+	c := &consistency{
+		p:              p,
+		table:          p.GetString(prop.TableName, prop.TableNameDefault),
+		recordCount:    p.GetInt64(prop.RecordCount, prop.RecordCountDefault),
+		orderedInserts: p.GetString(prop.InsertOrder, prop.InsertOrderDefault) != "hashed",
+		zeroPadding:    p.GetInt64(prop.ZeroPadding, prop.ZeroPaddingDefault),
+		insertStart:    p.GetInt64(prop.InsertStart, prop.InsertStartDefault),
+		start:          time.Now(),
+		checker:        newConsistencyChecker(),
+	}
+	if c.recordCount <= 0 {
+		return nil, fmt.Errorf("%s must be positive for the keys", prop.RecordCount)
+	}
+	// the versions of the previous runs are older than the versions of this run.
+	c.run = strconv.FormatInt(c.start.UnixNano(), 36)
+	c.writeMu = make([]sync.Mutex, c.recordCount)
+	c.attempted = make([]int64, c.recordCount)
+	c.acked = make([]int64, c.recordCount)
+
+	c.keySequence = generator.NewCounter(c.insertStart)
+	lastKeyNum := c.insertStart + c.recordCount - 1
+	requestDistrib := p.GetString(prop.RequestDistribution, prop.RequestDistributionDefault)
+	switch requestDistrib {
+	case "uniform":
+		c.keyChooser = generator.NewUniform(c.insertStart, lastKeyNum)
+	case "zipfian":
+		c.keyChooser = generator.NewScrambledZipfian(c.insertStart, lastKeyNum, generator.ZipfianConstant)
+	default:
+		return nil, fmt.Errorf("unsupported request distribution %s", requestDistrib)
+	}
+
+	readProportion := p.GetFloat64(prop.ReadProportion, prop.ReadProportionDefault)
+	updateProportion := p.GetFloat64(prop.UpdateProportion, prop.UpdateProportionDefault)
+	if readProportion <= 0 && updateProportion <= 0 {
+		return nil, fmt.Errorf("%s or %s must be positive", prop.ReadProportion, prop.UpdateProportion)
+	}
+	c.operationChooser = generator.NewDiscrete()
+	if readProportion > 0 {
+		c.operationChooser.Add(readProportion, int64(read))
+	}
+	if updateProportion > 0 {
+		c.operationChooser.Add(updateProportion, int64(update))
+	}
+
+	if name := p.GetString(prop.ConsistencyHistory, ""); name != "" {
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		c.f = f
+		c.w = bufio.NewWriter(f)
+		c.enc = json.NewEncoder(c.w)
+	}
+	return c, nil
+}
+
+func init() {
+	ycsb.RegisterWorkloadCreator("consistency", consistencyCreator{})
+}
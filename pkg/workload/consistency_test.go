@@ -0,0 +1,224 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// replicaDB writes to the primary and serves the reads from a replica,
+// which copies the primary every lag-th read.
+type replicaDB struct {
+	*memDB
+	replica map[string]map[string][]byte
+	lag     int
+	reads   int
+}
+
+func newReplicaDB(lag int) *replicaDB {
+	return &replicaDB{memDB: newMemDB(), replica: make(map[string]map[string][]byte), lag: lag}
+}
+
+func (db *replicaDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
+	db.reads++
+	if db.lag > 0 && db.reads%db.lag == 0 {
+		for key, values := range db.rows {
+			db.replica[key] = values
+		}
+	}
+	return db.replica[key], nil
+}
+
+func (db *replicaDB) Insert(ctx context.Context, table string, key string, values map[string][]byte) error {
+	db.replica[key] = values
+	return db.memDB.Insert(ctx, table, key, values)
+}
+
+func runConsistency(t *testing.T, db *replicaDB, history string) ConsistencyReport {
+	p := properties.NewProperties()
+	p.Set(prop.RecordCount, "10")
+	p.Set(prop.ReadProportion, "0.5")
+	p.Set(prop.UpdateProportion, "0.5")
+	p.Set(prop.RandomSeed, "1")
+	p.Set(prop.ConsistencyHistory, history)
+
+	w, ctx := newTestWorkload(t, consistencyCreator{}, p)
+	doInserts(t, w, ctx, db, 10)
+	doTransactions(t, w, ctx, db, 200)
+
+	err := w.(ycsb.Validator).Validate(ctx, db)
+	report := w.(*consistency).checker.result()
+	if report.AnomalyCount() > 0 && err == nil {
+		t.Fatal("the anomalies should fail the validation")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestConsistency(t *testing.T) {
+	dir := t.TempDir()
+
+	db := newReplicaDB(1)
+	report := runConsistency(t, db, filepath.Join(dir, "consistent"))
+	if report.Reads == 0 || report.Writes == 0 {
+		t.Fatalf("want reads and writes, but got %s", &report)
+	}
+	if n := report.AnomalyCount(); n != 0 {
+		t.Fatalf("want no anomalies, but got %s", &report)
+	}
+
+	db = newReplicaDB(5)
+	history := filepath.Join(dir, "lagging")
+	report = runConsistency(t, db, history)
+	if report.Anomalies[AnomalyReadYourWrites] == 0 {
+		t.Fatalf("want read-your-writes violations of the lagging replica, but got %s", &report)
+	}
+
+	// the recorded history has the same anomalies.
+	f, err := os.Open(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	checked, err := CheckHistory(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked.String() != report.String() {
+		t.Fatalf("want %s from the history, but got %s", &report, &checked)
+	}
+
+	// the writes lost by the replica are the lost updates.
+	db.lag = 0
+	db.rows = make(map[string]map[string][]byte)
+	report = runConsistency(t, db, "")
+	if report.Anomalies[AnomalyLostUpdate] == 0 {
+		t.Fatalf("want lost updates, but got %s", &report)
+	}
+}
+
+// syncDB is a linearizable DB, whose updates are delayed before they are
+// applied atomically, so the updates of a key overlap.
+type syncDB struct {
+	*memDB
+	mu sync.Mutex
+}
+
+func (db *syncDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	row, ok := db.rows[key]
+	if !ok {
+		return nil, errNotFound
+	}
+	return row, nil
+}
+
+func (db *syncDB) Insert(ctx context.Context, table string, key string, values map[string][]byte) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.memDB.Insert(ctx, table, key, values)
+}
+
+func (db *syncDB) Update(ctx context.Context, table string, key string, values map[string][]byte) error {
+	time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.rows[key]; !ok {
+		return errNotFound
+	}
+	return db.memDB.Update(ctx, table, key, values)
+}
+
+func TestConsistencyThreads(t *testing.T) {
+	p := properties.NewProperties()
+	p.Set(prop.RecordCount, "4")
+	p.Set(prop.InsertStart, "100")
+	p.Set(prop.ReadProportion, "0.5")
+	p.Set(prop.UpdateProportion, "0.5")
+
+	w, ctx := newTestWorkload(t, consistencyCreator{}, p)
+	db := &syncDB{memDB: newMemDB()}
+	// the keys are loaded from insertstart, the operations of the other keys
+	// fail.
+	doInserts(t, w, ctx, db, 4)
+
+	// the concurrent writes of a few keys on a linearizable DB are no
+	// anomalies.
+	threadCount := 8
+	errs := make(chan error, threadCount)
+	for i := 0; i < threadCount; i++ {
+		go func(threadID int) {
+			ctx := w.InitThread(context.Background(), threadID, threadCount)
+			for j := 0; j < 200; j++ {
+				if err := w.DoTransaction(ctx, db); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}(i)
+	}
+	for i := 0; i < threadCount; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.(ycsb.Validator).Validate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if report := w.(*consistency).checker.result(); report.Writes == 0 {
+		t.Fatalf("want writes, but got %s", &report)
+	}
+}
+
+func TestCheckHistory(t *testing.T) {
+	// the recorded acked and own versions are ignored, the read at 150 is
+	// after the ack of version 2 at 120, and the final version 1 is lost.
+	history := strings.Join([]string{
+		`{"t":0,"latency":50,"thread":0,"op":"WRITE","key":"k","version":1}`,
+		`{"t":100,"latency":20,"thread":1,"op":"WRITE","key":"k","version":2}`,
+		`{"t":110,"latency":5,"thread":0,"op":"READ","key":"k","version":1}`,
+		`{"t":150,"latency":5,"thread":0,"op":"READ","key":"k","version":1}`,
+		`{"t":160,"latency":5,"thread":1,"op":"READ","key":"k","version":1}`,
+		`{"t":200,"latency":5,"thread":-1,"op":"FINAL","key":"k","version":1}`,
+		// the failed write of version 2 may be applied after version 3.
+		`{"t":0,"latency":10,"thread":0,"op":"WRITE","key":"f","version":2,"err":"timeout"}`,
+		`{"t":20,"latency":10,"thread":1,"op":"WRITE","key":"f","version":3}`,
+		`{"t":50,"latency":5,"thread":1,"op":"READ","key":"f","version":2}`,
+		`{"t":200,"latency":5,"thread":-1,"op":"FINAL","key":"f","version":2}`,
+	}, "\n")
+	report, err := CheckHistory(strings.NewReader(history))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "4 reads, 3 writes, 3 anomalies, LOST_UPDATE: 1, READ_YOUR_WRITES_VIOLATION: 1, STALE_READ: 1"
+	if report.String() != want {
+		t.Fatalf("want %s, but got %s", want, &report)
+	}
+}
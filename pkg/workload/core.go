@@ -50,8 +50,13 @@ const (
 	insert
 	scan
 	readModifyWrite
+	del
 )
 
+// maxDeletedKeySkips is how many times the key chooser draws again if it
+// chooses a deleted key.
+const maxDeletedKeySkips = 16
+
 // Core is the core benchmark scenario. Represents a set of clients doing simple CRUD operations.
 type core struct {
 	p *properties.Properties
@@ -77,6 +82,13 @@ type core struct {
 	insertionRetryLimit          int64
 	insertionRetryInterval       int64
 
+	// deletedKeys are the keys deleted by the delete operations, which are
+	// skipped by the key chooser. They are only tracked in the process and
+	// never shrink, so the keys deleted by the other clients of a distributed
+	// run are still chosen.
+	deletedMu   sync.RWMutex
+	deletedKeys map[int64]struct{}
+
 	valuePool sync.Pool
 }
 
@@ -108,6 +120,7 @@ func createOperationGenerator(p *properties.Properties) *generator.Discrete {
 	insertProportion := p.GetFloat64(prop.InsertProportion, prop.InsertProportionDefault)
 	scanProportion := p.GetFloat64(prop.ScanProportion, prop.ScanProportionDefault)
 	readModifyWriteProportion := p.GetFloat64(prop.ReadModifyWriteProportion, prop.ReadModifyWriteProportionDefault)
+	deleteProportion := p.GetFloat64(prop.DeleteProportion, prop.DeleteProportionDefault)
 
 	operationChooser := generator.NewDiscrete()
 	if readProportion > 0 {
@@ -130,6 +143,10 @@ func createOperationGenerator(p *properties.Properties) *generator.Discrete {
 		operationChooser.Add(readModifyWriteProportion, int64(readModifyWrite))
 	}
 
+	if deleteProportion > 0 {
+		operationChooser.Add(deleteProportion, int64(del))
+	}
+
 	return operationChooser
 }
 
@@ -139,8 +156,8 @@ func (c *core) Load(ctx context.Context, db ycsb.DB, totalCount int64) error {
 }
 
 // InitThread implements the Workload InitThread interface.
-func (c *core) InitThread(ctx context.Context, _ int, _ int) context.Context {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+func (c *core) InitThread(ctx context.Context, threadID int, _ int) context.Context {
+	r := util.NewThreadRand(c.p, "core", threadID)
 	fieldNames := make([]string, len(c.fieldNames))
 	copy(fieldNames, c.fieldNames)
 	state := &coreState{
@@ -370,6 +387,8 @@ func (c *core) DoTransaction(ctx context.Context, db ycsb.DB) error {
 		return c.doTransactionInsert(ctx, db, state)
 	case scan:
 		return c.doTransactionScan(ctx, db, state)
+	case del:
+		return c.doTransactionDelete(ctx, db, state)
 	default:
 		return c.doTransactionReadModifyWrite(ctx, db, state)
 	}
@@ -392,6 +411,8 @@ func (c *core) DoBatchTransaction(ctx context.Context, batchSize int, db ycsb.DB
 		return c.doBatchTransactionInsert(ctx, batchSize, batchDB, state)
 	case update:
 		return c.doBatchTransactionUpdate(ctx, batchSize, batchDB, state)
+	case del:
+		return c.doBatchTransactionDelete(ctx, batchSize, batchDB, state)
 	case scan:
 		panic("The batch mode don't support the scan operation")
 	default:
@@ -399,7 +420,30 @@ func (c *core) DoBatchTransaction(ctx context.Context, batchSize int, db ycsb.DB
 	}
 }
 
+// nextKeyNum chooses the next key, and draws again if the key is deleted.
+// It still returns a deleted key after maxDeletedKeySkips draws, e.g. if
+// most of the hot keys are deleted.
 func (c *core) nextKeyNum(state *coreState) int64 {
+	keyNum := c.chooseKeyNum(state)
+	for i := 0; i < maxDeletedKeySkips && c.isDeleted(keyNum); i++ {
+		keyNum = c.chooseKeyNum(state)
+	}
+	return keyNum
+}
+
+// nextLiveKeyNum chooses the next key like nextKeyNum, but returns false if
+// the key is still deleted. The operation of a deleted key would miss, so it
+// is not issued but counted as <op>_EXPECTED_MISS.
+func (c *core) nextLiveKeyNum(ctx context.Context, state *coreState, op string) (int64, bool) {
+	keyNum := c.nextKeyNum(state)
+	if c.isDeleted(keyNum) {
+		measurement.MeasureContext(ctx, op+"_EXPECTED_MISS", time.Now(), 0)
+		return keyNum, false
+	}
+	return keyNum, true
+}
+
+func (c *core) chooseKeyNum(state *coreState) int64 {
 	r := state.r
 	keyNum := int64(0)
 	if _, ok := c.keyChooser.(*generator.Exponential); ok {
@@ -413,9 +457,44 @@ func (c *core) nextKeyNum(state *coreState) int64 {
 	return keyNum
 }
 
+func (c *core) isDeleted(keyNum int64) bool {
+	if c.deletedKeys == nil {
+		return false
+	}
+
+	c.deletedMu.RLock()
+	_, ok := c.deletedKeys[keyNum]
+	c.deletedMu.RUnlock()
+	return ok
+}
+
+// markDeleted marks the key deleted before deleting it, so the other threads
+// stop choosing it, and returns false if it is already deleted. The delete of
+// a deleted key would miss, so it is not issued but counted as
+// <op>_EXPECTED_MISS like the other operations.
+func (c *core) markDeleted(keyNum int64) bool {
+	c.deletedMu.Lock()
+	defer c.deletedMu.Unlock()
+	if _, ok := c.deletedKeys[keyNum]; ok {
+		return false
+	}
+	c.deletedKeys[keyNum] = struct{}{}
+	return true
+}
+
+// unmarkDeleted marks the key live again if the delete fails.
+func (c *core) unmarkDeleted(keyNum int64) {
+	c.deletedMu.Lock()
+	delete(c.deletedKeys, keyNum)
+	c.deletedMu.Unlock()
+}
+
 func (c *core) doTransactionRead(ctx context.Context, db ycsb.DB, state *coreState) error {
 	r := state.r
-	keyNum := c.nextKeyNum(state)
+	keyNum, ok := c.nextLiveKeyNum(ctx, state, "READ")
+	if !ok {
+		return nil
+	}
 	keyName := c.buildKeyName(keyNum)
 
 	var fields []string
@@ -438,14 +517,22 @@ func (c *core) doTransactionRead(ctx context.Context, db ycsb.DB, state *coreSta
 	return nil
 }
 
-func (c *core) doTransactionReadModifyWrite(ctx context.Context, db ycsb.DB, state *coreState) error {
+func (c *core) doTransactionReadModifyWrite(ctx context.Context, db ycsb.DB, state *coreState) (err error) {
+	keyNum, ok := c.nextLiveKeyNum(ctx, state, "READ_MODIFY_WRITE")
+	if !ok {
+		return nil
+	}
+
 	start := time.Now()
 	defer func() {
-		measurement.Measure("READ_MODIFY_WRITE", start, time.Now().Sub(start))
+		// don't report the operation interrupted by the stopping run
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		measurement.MeasureContext(ctx, "READ_MODIFY_WRITE", start, time.Now().Sub(start))
 	}()
 
 	r := state.r
-	keyNum := c.nextKeyNum(state)
 	keyName := c.buildKeyName(keyNum)
 
 	var fields []string
@@ -469,7 +556,7 @@ func (c *core) doTransactionReadModifyWrite(ctx context.Context, db ycsb.DB, sta
 		return err
 	}
 
-	if err := db.Update(ctx, c.table, keyName, values); err != nil {
+	if err = db.Update(ctx, c.table, keyName, values); err != nil {
 		return err
 	}
 
@@ -493,7 +580,10 @@ func (c *core) doTransactionInsert(ctx context.Context, db ycsb.DB, state *coreS
 
 func (c *core) doTransactionScan(ctx context.Context, db ycsb.DB, state *coreState) error {
 	r := state.r
-	keyNum := c.nextKeyNum(state)
+	keyNum, ok := c.nextLiveKeyNum(ctx, state, "SCAN")
+	if !ok {
+		return nil
+	}
 	startKeyName := c.buildKeyName(keyNum)
 
 	scanLen := c.scanLength.Next(r)
@@ -512,7 +602,10 @@ func (c *core) doTransactionScan(ctx context.Context, db ycsb.DB, state *coreSta
 }
 
 func (c *core) doTransactionUpdate(ctx context.Context, db ycsb.DB, state *coreState) error {
-	keyNum := c.nextKeyNum(state)
+	keyNum, ok := c.nextLiveKeyNum(ctx, state, "UPDATE")
+	if !ok {
+		return nil
+	}
 	keyName := c.buildKeyName(keyNum)
 
 	var values map[string][]byte
@@ -527,6 +620,20 @@ func (c *core) doTransactionUpdate(ctx context.Context, db ycsb.DB, state *coreS
 	return db.Update(ctx, c.table, keyName, values)
 }
 
+func (c *core) doTransactionDelete(ctx context.Context, db ycsb.DB, state *coreState) error {
+	keyNum := c.nextKeyNum(state)
+	if !c.markDeleted(keyNum) {
+		measurement.MeasureContext(ctx, "DELETE_EXPECTED_MISS", time.Now(), 0)
+		return nil
+	}
+
+	err := db.Delete(ctx, c.table, c.buildKeyName(keyNum))
+	if err != nil {
+		c.unmarkDeleted(keyNum)
+	}
+	return err
+}
+
 func (c *core) doBatchTransactionRead(ctx context.Context, batchSize int, db ycsb.BatchDB, state *coreState) error {
 	r := state.r
 	var fields []string
@@ -538,9 +645,15 @@ func (c *core) doBatchTransactionRead(ctx context.Context, batchSize int, db ycs
 		fields = state.fieldNames
 	}
 
-	keys := make([]string, batchSize)
+	// the deleted keys are left out of the batch.
+	keys := make([]string, 0, batchSize)
 	for i := 0; i < batchSize; i++ {
-		keys[i] = c.buildKeyName(c.nextKeyNum(state))
+		if keyNum, ok := c.nextLiveKeyNum(ctx, state, "BATCH_READ"); ok {
+			keys = append(keys, c.buildKeyName(keyNum))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
 	}
 
 	_, err := db.BatchRead(ctx, c.table, keys, fields)
@@ -578,18 +691,25 @@ func (c *core) doBatchTransactionInsert(ctx context.Context, batchSize int, db y
 }
 
 func (c *core) doBatchTransactionUpdate(ctx context.Context, batchSize int, db ycsb.BatchDB, state *coreState) error {
-	keys := make([]string, batchSize)
-	values := make([]map[string][]byte, batchSize)
+	// the deleted keys are left out of the batch.
+	keys := make([]string, 0, batchSize)
+	values := make([]map[string][]byte, 0, batchSize)
 	for i := 0; i < batchSize; i++ {
-		keyNum := c.nextKeyNum(state)
+		keyNum, ok := c.nextLiveKeyNum(ctx, state, "BATCH_UPDATE")
+		if !ok {
+			continue
+		}
 		keyName := c.buildKeyName(keyNum)
-		keys[i] = keyName
+		keys = append(keys, keyName)
 		if c.writeAllFields {
-			values[i] = c.buildValues(state, keyName)
+			values = append(values, c.buildValues(state, keyName))
 		} else {
-			values[i] = c.buildSingleValue(state, keyName)
+			values = append(values, c.buildSingleValue(state, keyName))
 		}
 	}
+	if len(keys) == 0 {
+		return nil
+	}
 
 	defer func() {
 		for _, value := range values {
@@ -600,6 +720,32 @@ func (c *core) doBatchTransactionUpdate(ctx context.Context, batchSize int, db y
 	return db.BatchUpdate(ctx, c.table, keys, values)
 }
 
+func (c *core) doBatchTransactionDelete(ctx context.Context, batchSize int, db ycsb.BatchDB, state *coreState) error {
+	keyNums := make([]int64, 0, batchSize)
+	keys := make([]string, 0, batchSize)
+	for i := 0; i < batchSize; i++ {
+		keyNum := c.nextKeyNum(state)
+		if !c.markDeleted(keyNum) {
+			measurement.MeasureContext(ctx, "BATCH_DELETE_EXPECTED_MISS", time.Now(), 0)
+			continue
+		}
+		keyNums = append(keyNums, keyNum)
+		keys = append(keys, c.buildKeyName(keyNum))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := db.BatchDelete(ctx, c.table, keys)
+	if err != nil {
+		// some of the keys may be deleted, but they are chosen again.
+		for _, keyNum := range keyNums {
+			c.unmarkDeleted(keyNum)
+		}
+	}
+	return err
+}
+
 // CoreCreator creates the Core workload.
 type coreCreator struct {
 }
@@ -648,10 +794,13 @@ func (coreCreator) Create(p *properties.Properties) (ycsb.Workload, error) {
 
 	c.keySequence = generator.NewCounter(insertStart)
 	c.operationChooser = createOperationGenerator(p)
+	if p.GetFloat64(prop.DeleteProportion, prop.DeleteProportionDefault) > 0 {
+		c.deletedKeys = make(map[int64]struct{})
+	}
 	var keyrangeLowerBound int64 = insertStart
 	var keyrangeUpperBound int64 = insertStart + insertCount - 1
 
-	c.transactionInsertKeySequence = generator.NewAcknowledgedCounter(c.recordCount)
+	c.transactionInsertKeySequence = generator.NewAcknowledgedCounter(p.GetInt64(prop.TransactionInsertStart, c.recordCount))
 	switch requestDistrib {
 	case "uniform":
 		c.keyChooser = generator.NewUniform(keyrangeLowerBound, keyrangeUpperBound)
@@ -673,6 +822,14 @@ func (coreCreator) Create(p *properties.Properties) (ycsb.Workload, error) {
 		percentile := p.GetFloat64(prop.ExponentialPercentile, prop.ExponentialPercentileDefault)
 		frac := p.GetFloat64(prop.ExponentialFrac, prop.ExponentialFracDefault)
 		c.keyChooser = generator.NewExponential(percentile, float64(c.recordCount)*frac)
+	case "trace", "file":
+		traceFile := p.GetString(prop.RequestDistributionFile, "")
+		traceType := p.GetString(prop.RequestDistributionFileType, prop.RequestDistributionFileTypeDefault)
+		keyChooser, err := generator.NewTraceFromFile(traceFile, traceType, keyrangeLowerBound, keyrangeUpperBound)
+		if err != nil {
+			util.Fatalf("load request distribution file %s failed %v", traceFile, err)
+		}
+		c.keyChooser = keyChooser
 	default:
 		util.Fatalf("unknown request distribution %s", requestDistrib)
 	}
@@ -0,0 +1,92 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+var errNotFound = errors.New("not found")
+
+// deleteDB fails the operations of the deleted keys.
+type deleteDB struct {
+	ycsb.DB
+	deleted map[string]int
+}
+
+func (db *deleteDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
+	if db.deleted[key] > 0 {
+		return nil, errNotFound
+	}
+	return nil, nil
+}
+
+func (db *deleteDB) Scan(ctx context.Context, table string, startKey string, count int, fields []string) ([]map[string][]byte, error) {
+	if db.deleted[startKey] > 0 {
+		return nil, errNotFound
+	}
+	return nil, nil
+}
+
+func (db *deleteDB) Update(ctx context.Context, table string, key string, values map[string][]byte) error {
+	if db.deleted[key] > 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+func (db *deleteDB) Delete(ctx context.Context, table string, key string) error {
+	db.deleted[key]++
+	if db.deleted[key] > 1 {
+		return errNotFound
+	}
+	return nil
+}
+
+func TestCoreDelete(t *testing.T) {
+	p := properties.NewProperties()
+	p.Set(prop.RecordCount, "20")
+	p.Set(prop.ReadProportion, "0.2")
+	p.Set(prop.UpdateProportion, "0.2")
+	p.Set(prop.ScanProportion, "0.2")
+	p.Set(prop.ReadModifyWriteProportion, "0.2")
+	p.Set(prop.DeleteProportion, "0.2")
+	p.Set(prop.RandomSeed, "1")
+
+	w, ctx := newTestWorkload(t, coreCreator{}, p)
+	db := &deleteDB{deleted: make(map[string]int)}
+	// more deletes than records, the operations of the deleted keys are
+	// expected misses which don't reach the DB, and every key is deleted
+	// only once.
+	doTransactions(t, w, ctx, db, 400)
+	if len(db.deleted) < 10 {
+		t.Fatalf("want most of the 20 keys deleted, but got %d", len(db.deleted))
+	}
+	stats, err := measurement.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []string{"READ_EXPECTED_MISS", "DELETE_EXPECTED_MISS"} {
+		if _, ok := stats[op]; !ok {
+			t.Fatalf("want %s, but got %v", op, stats)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// memDB keeps the records in memory, the fakes of the workload tests embed
+// it and override the operations they test. The operations of a missing
+// record don't fail.
+type memDB struct {
+	ycsb.DB
+	rows map[string]map[string][]byte
+}
+
+func newMemDB() *memDB {
+	return &memDB{rows: make(map[string]map[string][]byte)}
+}
+
+func (db *memDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
+	return db.rows[key], nil
+}
+
+func (db *memDB) Scan(ctx context.Context, table string, startKey string, count int, fields []string) ([]map[string][]byte, error) {
+	keys := make([]string, 0, len(db.rows))
+	for key := range db.rows {
+		if key >= startKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var rows []map[string][]byte
+	for i := 0; i < count && i < len(keys); i++ {
+		rows = append(rows, db.rows[keys[i]])
+	}
+	return rows, nil
+}
+
+func (db *memDB) Insert(ctx context.Context, table string, key string, values map[string][]byte) error {
+	db.rows[key] = values
+	return nil
+}
+
+func (db *memDB) Update(ctx context.Context, table string, key string, values map[string][]byte) error {
+	// the updated record is a new map, which keeps the records read before.
+	row := make(map[string][]byte, len(values))
+	for field, value := range db.rows[key] {
+		row[field] = value
+	}
+	for field, value := range values {
+		row[field] = value
+	}
+	db.rows[key] = row
+	return nil
+}
+
+func (db *memDB) Delete(ctx context.Context, table string, key string) error {
+	delete(db.rows, key)
+	return nil
+}
+
+// newTestWorkload creates the workload with a new measurement, and returns
+// it with the context of its only thread.
+func newTestWorkload(t *testing.T, creator ycsb.WorkloadCreator, p *properties.Properties) (ycsb.Workload, context.Context) {
+	measurement.InitMeasure(p)
+	w, err := creator.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		w.Close()
+	})
+	return w, w.InitThread(context.Background(), 0, 1)
+}
+
+// doInserts loads n records with the workload.
+func doInserts(t *testing.T, w ycsb.Workload, ctx context.Context, db ycsb.DB, n int) {
+	for i := 0; i < n; i++ {
+		if err := w.DoInsert(ctx, db); err != nil {
+			t.Fatalf("insert %d failed %v", i, err)
+		}
+	}
+}
+
+// doTransactions runs n operations of the workload.
+func doTransactions(t *testing.T, w ycsb.Workload, ctx context.Context, db ycsb.DB, n int) {
+	for i := 0; i < n; i++ {
+		if err := w.DoTransaction(ctx, db); err != nil {
+			t.Fatalf("operation %d failed %v", i, err)
+		}
+	}
+}
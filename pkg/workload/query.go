@@ -0,0 +1,360 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/generator"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+const queryStateKey = contextKey("query")
+
+type queryState struct {
+	r *rand.Rand
+}
+
+// queryStats counts the queries of a predicate and the rows they return.
+type queryStats struct {
+	queries int64
+	rows    int64
+}
+
+func (s *queryStats) String() string {
+	queries := atomic.LoadInt64(&s.queries)
+	rows := atomic.LoadInt64(&s.rows)
+	var avg float64
+	if queries > 0 {
+		avg = float64(rows) / float64(queries)
+	}
+	return fmt.Sprintf("%d queries, %d rows, %.1f rows per query", queries, rows, avg)
+}
+
+// query queries the records by the predicates on the non-key query.fields,
+// which are indexed by the ycsb.QueryDB before the first record is loaded.
+// The values of a query field are the numbers in [0, query.cardinality) of
+// the same width, so their order is the order of the bytes. The reads are
+// the equality predicates and the scans the range predicates of
+// query.rangelength values, and the updates change a query field of a record
+// to a new value for the index maintenance. The latencies of the queries are
+// measured as QUERY by the DB wrapper, and the rows they return are counted
+// by the predicates and printed at Close.
+type query struct {
+	p *properties.Properties
+
+	table          string
+	recordCount    int64
+	fieldCount     int64
+	fieldLength    int64
+	queryFields    []string
+	cardinality    int64
+	valueWidth     int
+	rangeLength    int64
+	limit          int
+	orderedInserts bool
+	zeroPadding    int64
+
+	keySequence      ycsb.Generator
+	keyChooser       ycsb.Generator
+	valueChooser     ycsb.Generator
+	operationChooser *generator.Discrete
+
+	indexOnce sync.Once
+	indexErr  error
+
+	equal     queryStats
+	rng       queryStats
+	wrongRows int64
+}
+
+// Load implements the Workload Load interface.
+func (q *query) Load(ctx context.Context, db ycsb.DB, totalCount int64) error {
+	return nil
+}
+
+// InitThread implements the Workload InitThread interface.
+func (q *query) InitThread(ctx context.Context, threadID int, _ int) context.Context {
+	state := &queryState{r: util.NewThreadRand(q.p, "query", threadID)}
+	return context.WithValue(ctx, queryStateKey, state)
+}
+
+// CleanupThread implements the Workload CleanupThread interface.
+func (q *query) CleanupThread(_ context.Context) {
+}
+
+// Close implements the Workload Close interface, it prints the rows returned
+// by the queries.
+func (q *query) Close() error {
+	if atomic.LoadInt64(&q.equal.queries)+atomic.LoadInt64(&q.rng.queries) > 0 {
+		fmt.Printf("Query: equality predicates %s, range predicates %s\n", &q.equal, &q.rng)
+	}
+	return nil
+}
+
+func (q *query) buildKeyName(keyNum int64) string {
+	if !q.orderedInserts {
+		keyNum = util.Hash64(keyNum)
+	}
+
+	prefix := q.p.GetString(prop.KeyPrefix, prop.KeyPrefixDefault)
+	return fmt.Sprintf("%s%0[3]*[2]d", prefix, keyNum, q.zeroPadding)
+}
+
+func (q *query) buildValue(value int64) []byte {
+	return []byte(fmt.Sprintf("%0*d", q.valueWidth, value))
+}
+
+// isQueryField returns whether the field has the values of the predicates.
+func (q *query) isQueryField(field string) bool {
+	for _, f := range q.queryFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *query) buildValues(state *queryState) map[string][]byte {
+	values := make(map[string][]byte, q.fieldCount)
+	for i := int64(0); i < q.fieldCount; i++ {
+		field := fmt.Sprintf("field%d", i)
+		if q.isQueryField(field) {
+			values[field] = q.buildValue(state.r.Int63n(q.cardinality))
+			continue
+		}
+		buf := make([]byte, q.fieldLength)
+		util.RandBytes(state.r, buf)
+		values[field] = buf
+	}
+	return values
+}
+
+// createIndexes creates the indexes of the query fields once before the
+// first record is loaded, and returns the error of the creation to every
+// insert, the load can't go on without the indexes.
+func (q *query) createIndexes(ctx context.Context, db ycsb.DB) error {
+	q.indexOnce.Do(func() {
+		queryDB, ok := db.(ycsb.QueryDB)
+		if !ok {
+			q.indexErr = fmt.Errorf("the %T doesn't implement the QueryDB interface", db)
+			return
+		}
+		for _, field := range q.queryFields {
+			if err := queryDB.CreateIndex(ctx, q.table, field); err != nil {
+				q.indexErr = fmt.Errorf("create index of %s failed %v", field, err)
+				return
+			}
+		}
+	})
+	return q.indexErr
+}
+
+// DoInsert implements the Workload DoInsert interface.
+func (q *query) DoInsert(ctx context.Context, db ycsb.DB) error {
+	if err := q.createIndexes(ctx, db); err != nil {
+		return err
+	}
+	state := ctx.Value(queryStateKey).(*queryState)
+	key := q.buildKeyName(q.keySequence.Next(state.r))
+	return db.Insert(ctx, q.table, key, q.buildValues(state))
+}
+
+// DoBatchInsert implements the Workload DoBatchInsert interface.
+func (q *query) DoBatchInsert(ctx context.Context, batchSize int, db ycsb.DB) error {
+	batchDB, ok := db.(ycsb.BatchDB)
+	if !ok {
+		return fmt.Errorf("the %T doesn't implement the batchDB interface", db)
+	}
+	if err := q.createIndexes(ctx, db); err != nil {
+		return err
+	}
+	state := ctx.Value(queryStateKey).(*queryState)
+
+	keys := make([]string, batchSize)
+	values := make([]map[string][]byte, batchSize)
+	for i := 0; i < batchSize; i++ {
+		keys[i] = q.buildKeyName(q.keySequence.Next(state.r))
+		values[i] = q.buildValues(state)
+	}
+	return batchDB.BatchInsert(ctx, q.table, keys, values)
+}
+
+// DoTransaction implements the Workload DoTransaction interface.
+func (q *query) DoTransaction(ctx context.Context, db ycsb.DB) error {
+	state := ctx.Value(queryStateKey).(*queryState)
+	field := q.queryFields[state.r.Intn(len(q.queryFields))]
+
+	switch operationType(q.operationChooser.Next(state.r)) {
+	case read:
+		value := q.valueChooser.Next(state.r)
+		return q.doQuery(ctx, db, &q.equal, field, value, value)
+	case scan:
+		low := q.valueChooser.Next(state.r)
+		high := low + q.rangeLength - 1
+		if high >= q.cardinality {
+			high = q.cardinality - 1
+		}
+		return q.doQuery(ctx, db, &q.rng, field, low, high)
+	default:
+		key := q.buildKeyName(q.keyChooser.Next(state.r))
+		values := map[string][]byte{field: q.buildValue(state.r.Int63n(q.cardinality))}
+		return db.Update(ctx, q.table, key, values)
+	}
+}
+
+// DoBatchTransaction implements the Workload DoBatchTransaction interface,
+// the operations of the batch run one by one.
+func (q *query) DoBatchTransaction(ctx context.Context, batchSize int, db ycsb.DB) error {
+	for i := 0; i < batchSize; i++ {
+		if err := q.DoTransaction(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldValue returns the value of the field in the row, whose columns may be
+// upper case in a SQL DB.
+func fieldValue(row map[string][]byte, field string) ([]byte, bool) {
+	if value, ok := row[field]; ok {
+		return value, true
+	}
+	value, ok := row[strings.ToUpper(field)]
+	return value, ok
+}
+
+func (q *query) doQuery(ctx context.Context, db ycsb.DB, stats *queryStats, field string, low int64, high int64) error {
+	queryDB, ok := db.(ycsb.QueryDB)
+	if !ok {
+		return fmt.Errorf("the %T doesn't implement the QueryDB interface", db)
+	}
+
+	lowValue, highValue := q.buildValue(low), q.buildValue(high)
+	rows, err := queryDB.Query(ctx, q.table, field, lowValue, highValue, q.limit, nil)
+	if err != nil {
+		return err
+	}
+
+	atomic.AddInt64(&stats.queries, 1)
+	atomic.AddInt64(&stats.rows, int64(len(rows)))
+	for _, row := range rows {
+		value, ok := fieldValue(row, field)
+		if !ok || bytes.Compare(value, lowValue) < 0 || bytes.Compare(value, highValue) > 0 {
+			atomic.AddInt64(&q.wrongRows, 1)
+		}
+	}
+	return nil
+}
+
+// Validate implements the Validator Validate interface, it fails if any row
+// returned by the queries of the run doesn't match its predicate.
+func (q *query) Validate(ctx context.Context, db ycsb.DB) error {
+	if n := atomic.LoadInt64(&q.wrongRows); n > 0 {
+		return fmt.Errorf("%d rows don't match the predicates of their queries", n)
+	}
+	return nil
+}
+
+type queryCreator struct {
+}
+
+// Create implements the WorkloadCreator Create interface.
+func (queryCreator) Create(p *properties.Properties) (ycsb.Workload, error) {
+	q := &query{
+		p:              p,
+		table:          p.GetString(prop.TableName, prop.TableNameDefault),
+		recordCount:    p.GetInt64(prop.RecordCount, prop.RecordCountDefault),
+		fieldCount:     p.GetInt64(prop.FieldCount, prop.FieldCountDefault),
+		fieldLength:    p.GetInt64(prop.FieldLength, prop.FieldLengthDefault),
+		cardinality:    p.GetInt64(prop.QueryCardinality, prop.QueryCardinalityDefault),
+		rangeLength:    p.GetInt64(prop.QueryRangeLength, prop.QueryRangeLengthDefault),
+		limit:          int(p.GetInt64(prop.QueryLimit, prop.QueryLimitDefault)),
+		orderedInserts: p.GetString(prop.InsertOrder, prop.InsertOrderDefault) != "hashed",
+		zeroPadding:    p.GetInt64(prop.ZeroPadding, prop.ZeroPaddingDefault),
+	}
+	if q.recordCount <= 0 {
+		return nil, fmt.Errorf("%s must be positive for the records", prop.RecordCount)
+	}
+	if q.cardinality <= 0 || q.rangeLength <= 0 || q.limit <= 0 {
+		return nil, fmt.Errorf("%s, %s and %s must be positive", prop.QueryCardinality, prop.QueryRangeLength, prop.QueryLimit)
+	}
+	q.valueWidth = len(strconv.FormatInt(q.cardinality-1, 10))
+	if int64(q.valueWidth) > q.fieldLength {
+		return nil, fmt.Errorf("the values of %s %d are longer than %s %d",
+			prop.QueryCardinality, q.cardinality, prop.FieldLength, q.fieldLength)
+	}
+
+	for _, field := range strings.Split(p.GetString(prop.QueryFields, prop.QueryFieldsDefault), ",") {
+		field = strings.TrimSpace(field)
+		if len(field) == 0 {
+			continue
+		}
+		i, err := strconv.ParseInt(strings.TrimPrefix(field, "field"), 10, 64)
+		if !strings.HasPrefix(field, "field") || err != nil || i < 0 || i >= q.fieldCount {
+			return nil, fmt.Errorf("%s must be in field0 to field%d, but got %s", prop.QueryFields, q.fieldCount-1, field)
+		}
+		q.queryFields = append(q.queryFields, field)
+	}
+	if len(q.queryFields) == 0 {
+		return nil, fmt.Errorf("%s must not be empty", prop.QueryFields)
+	}
+
+	q.keySequence = generator.NewCounter(p.GetInt64(prop.InsertStart, prop.InsertStartDefault))
+	requestDistrib := p.GetString(prop.RequestDistribution, prop.RequestDistributionDefault)
+	switch requestDistrib {
+	case "uniform":
+		q.keyChooser = generator.NewUniform(0, q.recordCount-1)
+		q.valueChooser = generator.NewUniform(0, q.cardinality-1)
+	case "zipfian":
+		q.keyChooser = generator.NewScrambledZipfian(0, q.recordCount-1, generator.ZipfianConstant)
+		q.valueChooser = generator.NewScrambledZipfian(0, q.cardinality-1, generator.ZipfianConstant)
+	default:
+		return nil, fmt.Errorf("unsupported request distribution %s", requestDistrib)
+	}
+
+	// the reads are the equality predicates and the scans the range ones.
+	readProportion := p.GetFloat64(prop.ReadProportion, prop.ReadProportionDefault)
+	scanProportion := p.GetFloat64(prop.ScanProportion, prop.ScanProportionDefault)
+	updateProportion := p.GetFloat64(prop.UpdateProportion, prop.UpdateProportionDefault)
+	if readProportion <= 0 && scanProportion <= 0 && updateProportion <= 0 {
+		return nil, fmt.Errorf("%s, %s or %s must be positive", prop.ReadProportion, prop.ScanProportion, prop.UpdateProportion)
+	}
+	q.operationChooser = generator.NewDiscrete()
+	if readProportion > 0 {
+		q.operationChooser.Add(readProportion, int64(read))
+	}
+	if scanProportion > 0 {
+		q.operationChooser.Add(scanProportion, int64(scan))
+	}
+	if updateProportion > 0 {
+		q.operationChooser.Add(updateProportion, int64(update))
+	}
+
+	return q, nil
+}
+
+func init() {
+	ycsb.RegisterWorkloadCreator("query", queryCreator{})
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// indexDB queries the records in memory by scanning them all, and returns
+// every record if ignorePredicate is set.
+type indexDB struct {
+	*memDB
+	indexes         map[string]int
+	ignorePredicate bool
+}
+
+func (db *indexDB) CreateIndex(ctx context.Context, table string, field string) error {
+	db.indexes[field]++
+	return nil
+}
+
+func (db *indexDB) Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) ([]map[string][]byte, error) {
+	var rows []map[string][]byte
+	for _, row := range db.rows {
+		if len(rows) == count {
+			break
+		}
+		value := row[field]
+		if db.ignorePredicate || (bytes.Compare(value, low) >= 0 && bytes.Compare(value, high) <= 0) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func TestQuery(t *testing.T) {
+	p := properties.NewProperties()
+	p.Set(prop.RecordCount, "100")
+	p.Set(prop.FieldCount, "4")
+	p.Set(prop.FieldLength, "8")
+	p.Set(prop.QueryFields, "field1, field3")
+	p.Set(prop.QueryCardinality, "20")
+	p.Set(prop.QueryRangeLength, "5")
+	p.Set(prop.ReadProportion, "0.4")
+	p.Set(prop.ScanProportion, "0.4")
+	p.Set(prop.UpdateProportion, "0.2")
+	p.Set(prop.RandomSeed, "1")
+
+	w, ctx := newTestWorkload(t, queryCreator{}, p)
+	db := &indexDB{memDB: newMemDB(), indexes: make(map[string]int)}
+	doInserts(t, w, ctx, db, 100)
+	if len(db.indexes) != 2 || db.indexes["field1"] != 1 || db.indexes["field3"] != 1 {
+		t.Fatalf("want the indexes of field1 and field3 created once, but got %v", db.indexes)
+	}
+	for _, row := range db.rows {
+		if value := row["field1"]; len(value) != 2 || bytes.Compare(value, []byte("19")) > 0 {
+			t.Fatalf("want a value in [00, 19], but got %q", value)
+		}
+	}
+
+	doTransactions(t, w, ctx, db, 300)
+	q := w.(*query)
+	if q.equal.queries == 0 || q.rng.queries == 0 || q.equal.queries+q.rng.queries >= 300 {
+		t.Fatalf("want the queries and updates, but got %d equality and %d range queries", q.equal.queries, q.rng.queries)
+	}
+	// every range covers 5 values of the 20, the equality predicates 1.
+	if q.rng.rows <= q.equal.rows {
+		t.Fatalf("want more rows of the range predicates, but got %s and %s", &q.rng, &q.equal)
+	}
+	// the rows are counted by the workload, not measured as the latencies.
+	stats, err := measurement.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for op := range stats {
+		if strings.HasSuffix(op, "_ROWS") {
+			t.Fatalf("want no measured rows, but got %s", op)
+		}
+	}
+	if err := w.(ycsb.Validator).Validate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	// the rows which don't match the predicates fail the validation.
+	db.ignorePredicate = true
+	doTransactions(t, w, ctx, db, 10)
+	if err := w.(ycsb.Validator).Validate(ctx, db); err == nil {
+		t.Fatal("the rows of the other values should fail the validation")
+	}
+
+	// the load fails without the indexes.
+	w, ctx = newTestWorkload(t, queryCreator{}, p)
+	if err := w.DoInsert(ctx, newMemDB()); err == nil {
+		t.Fatal("the DB without the indexes should fail the load")
+	}
+
+	p.Set(prop.QueryFields, "field4")
+	if _, err := (queryCreator{}).Create(p); err == nil {
+		t.Fatal("the field out of fieldcount should fail")
+	}
+}
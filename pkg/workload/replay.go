@@ -0,0 +1,244 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+const replayStateKey = contextKey("replay")
+
+// unkeyedOps are the recorded operations which can't be replayed, because
+// their arguments besides the keys are not recorded, e.g. the predicate of
+// a QUERY. The transaction boundaries are not recorded by the record
+// middleware, but may be in the traces of other tools.
+var unkeyedOps = map[string]bool{
+	"QUERY":  true,
+	"BEGIN":  true,
+	"COMMIT": true,
+	"ABORT":  true,
+}
+
+type replayState struct {
+	r *rand.Rand
+}
+
+// replay issues the operations recorded in replay.file by the record
+// middleware again. The workers take the operations in the recorded order,
+// so the operation stream is identical with a single thread, and at most
+// interleaved differently with more threads. If replay.timing is set, every
+// operation waits until its recorded time since the first one. The unkeyedOps
+// are skipped.
+type replay struct {
+	timing bool
+
+	mu        sync.Mutex
+	f         *os.File
+	dec       *json.Decoder
+	finished  bool
+	start     time.Time
+	firstTime int64
+	skipped   map[string]int64
+}
+
+// Load implements the Workload Load interface.
+func (r *replay) Load(ctx context.Context, db ycsb.DB, totalCount int64) error {
+	return nil
+}
+
+// InitThread implements the Workload InitThread interface.
+func (r *replay) InitThread(ctx context.Context, threadID int, _ int) context.Context {
+	// the values are random, but the same in every replay.
+	state := &replayState{r: rand.New(rand.NewSource(int64(threadID)))}
+	return context.WithValue(ctx, replayStateKey, state)
+}
+
+// CleanupThread implements the Workload CleanupThread interface.
+func (r *replay) CleanupThread(_ context.Context) {
+}
+
+// Close implements the Workload Close interface.
+func (r *replay) Close() error {
+	for op, n := range r.skipped {
+		fmt.Printf("%d %s operations are skipped\n", n, op)
+	}
+	return r.f.Close()
+}
+
+// next returns the next operation of the trace, or ycsb.ErrWorkloadFinished
+// at the end of the trace.
+func (r *replay) next() (*ycsb.TraceOp, time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.finished {
+		return nil, time.Time{}, ycsb.ErrWorkloadFinished
+	}
+
+	op := new(ycsb.TraceOp)
+	for {
+		if err := r.dec.Decode(op); err != nil {
+			r.finished = true
+			if err == io.EOF {
+				return nil, time.Time{}, ycsb.ErrWorkloadFinished
+			}
+			return nil, time.Time{}, fmt.Errorf("read trace failed %v", err)
+		}
+		if !unkeyedOps[op.Op] {
+			break
+		}
+		r.skipped[op.Op]++
+		*op = ycsb.TraceOp{}
+	}
+
+	if r.start.IsZero() {
+		r.start = time.Now()
+		r.firstTime = op.Time
+	}
+	return op, r.start.Add(time.Duration(op.Time-r.firstTime) * time.Microsecond), nil
+}
+
+func (r *replay) buildValues(state *replayState, sizes map[string]int) map[string][]byte {
+	values := make(map[string][]byte, len(sizes))
+	for field, size := range sizes {
+		buf := make([]byte, size)
+		util.RandBytes(state.r, buf)
+		values[field] = buf
+	}
+	return values
+}
+
+func (r *replay) do(ctx context.Context, db ycsb.DB) error {
+	op, at, err := r.next()
+	if err != nil {
+		return err
+	}
+
+	if r.timing {
+		if d := time.Until(at); d > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d):
+			}
+		}
+	}
+
+	if len(op.Keys) == 0 {
+		return fmt.Errorf("%s has no key", op.Op)
+	}
+	state := ctx.Value(replayStateKey).(*replayState)
+	var values []map[string][]byte
+	for _, sizes := range op.Sizes {
+		values = append(values, r.buildValues(state, sizes))
+	}
+	switch op.Op {
+	case "INSERT", "UPDATE", "BATCH_INSERT", "BATCH_UPDATE":
+		if len(values) != len(op.Keys) {
+			return fmt.Errorf("%s has %d keys but %d values", op.Op, len(op.Keys), len(values))
+		}
+	}
+
+	switch op.Op {
+	case "READ":
+		_, err = db.Read(ctx, op.Table, op.Keys[0], op.Fields)
+	case "SCAN":
+		_, err = db.Scan(ctx, op.Table, op.Keys[0], op.Count, op.Fields)
+	case "INSERT":
+		err = db.Insert(ctx, op.Table, op.Keys[0], values[0])
+	case "UPDATE":
+		err = db.Update(ctx, op.Table, op.Keys[0], values[0])
+	case "DELETE":
+		err = db.Delete(ctx, op.Table, op.Keys[0])
+	default:
+		batchDB, ok := db.(ycsb.BatchDB)
+		if !ok {
+			return fmt.Errorf("%s needs a batch db", op.Op)
+		}
+		switch op.Op {
+		case "BATCH_READ":
+			_, err = batchDB.BatchRead(ctx, op.Table, op.Keys, op.Fields)
+		case "BATCH_INSERT":
+			err = batchDB.BatchInsert(ctx, op.Table, op.Keys, values)
+		case "BATCH_UPDATE":
+			err = batchDB.BatchUpdate(ctx, op.Table, op.Keys, values)
+		case "BATCH_DELETE":
+			err = batchDB.BatchDelete(ctx, op.Table, op.Keys)
+		default:
+			return fmt.Errorf("unknown operation %s in the trace", op.Op)
+		}
+	}
+	return err
+}
+
+// DoInsert implements the Workload DoInsert interface.
+func (r *replay) DoInsert(ctx context.Context, db ycsb.DB) error {
+	return r.do(ctx, db)
+}
+
+// DoBatchInsert implements the Workload DoBatchInsert interface, the batches
+// are replayed as they are recorded.
+func (r *replay) DoBatchInsert(ctx context.Context, batchSize int, db ycsb.DB) error {
+	return r.do(ctx, db)
+}
+
+// DoTransaction implements the Workload DoTransaction interface.
+func (r *replay) DoTransaction(ctx context.Context, db ycsb.DB) error {
+	return r.do(ctx, db)
+}
+
+// DoBatchTransaction implements the Workload DoBatchTransaction interface,
+// the batches are replayed as they are recorded.
+func (r *replay) DoBatchTransaction(ctx context.Context, batchSize int, db ycsb.DB) error {
+	return r.do(ctx, db)
+}
+
+type replayCreator struct {
+}
+
+// Create implements the WorkloadCreator Create interface.
+func (replayCreator) Create(p *properties.Properties) (ycsb.Workload, error) {
+	name := p.GetString(prop.ReplayFile, "")
+	if name == "" {
+		return nil, fmt.Errorf("%s is not set", prop.ReplayFile)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &replay{
+		timing:  p.GetBool(prop.ReplayTiming, prop.ReplayTimingDefault),
+		f:       f,
+		dec:     json.NewDecoder(bufio.NewReader(f)),
+		skipped: make(map[string]int64),
+	}, nil
+}
+
+func init() {
+	ycsb.RegisterWorkloadCreator("replay", replayCreator{})
+}
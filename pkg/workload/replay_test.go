@@ -0,0 +1,101 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+// logDB logs the operations issued to it.
+type logDB struct {
+	ycsb.DB
+	ops []string
+}
+
+func (db *logDB) Read(ctx context.Context, table string, key string, fields []string) (map[string][]byte, error) {
+	db.ops = append(db.ops, fmt.Sprintf("READ %s %s %v", table, key, fields))
+	return nil, nil
+}
+
+func (db *logDB) Scan(ctx context.Context, table string, startKey string, count int, fields []string) ([]map[string][]byte, error) {
+	db.ops = append(db.ops, fmt.Sprintf("SCAN %s %s %d", table, startKey, count))
+	return nil, nil
+}
+
+func (db *logDB) Update(ctx context.Context, table string, key string, values map[string][]byte) error {
+	db.ops = append(db.ops, fmt.Sprintf("UPDATE %s %s %d", table, key, len(values["field0"])))
+	return nil
+}
+
+func TestReplay(t *testing.T) {
+	trace := filepath.Join(t.TempDir(), "trace.jsonl")
+	if err := os.WriteFile(trace, []byte(strings.Join([]string{
+		`{"t":1000,"op":"READ","table":"usertable","keys":["user1"],"fields":["field1"]}`,
+		`{"t":50000,"op":"BEGIN","table":"","keys":null}`,
+		`{"t":101000,"op":"UPDATE","table":"usertable","keys":["user2"],"sizes":[{"field0":10}]}`,
+		`{"t":101500,"op":"COMMIT","table":"","keys":null}`,
+		`{"t":101800,"op":"QUERY","table":"usertable","keys":null,"count":10}`,
+		`{"t":102000,"op":"SCAN","table":"usertable","keys":["user3"],"count":5}`,
+	}, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := properties.NewProperties()
+	p.Set(prop.ReplayFile, trace)
+	p.Set(prop.ReplayTiming, "true")
+	w, err := replayCreator{}.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	db := new(logDB)
+	ctx := w.InitThread(context.Background(), 0, 1)
+	start := time.Now()
+	for {
+		err := w.DoTransaction(ctx, db)
+		if err == ycsb.ErrWorkloadFinished {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("the replay should take the recorded 100ms, but takes %s", elapsed)
+	}
+	want := []string{
+		"READ usertable user1 [field1]",
+		"UPDATE usertable user2 10",
+		"SCAN usertable user3 5",
+	}
+	if fmt.Sprint(db.ops) != fmt.Sprint(want) {
+		t.Fatalf("want %v, but got %v", want, db.ops)
+	}
+	// the operations without keys are skipped.
+	if skipped := w.(*replay).skipped; len(skipped) != 3 || skipped["QUERY"] != 1 {
+		t.Fatalf("want BEGIN, COMMIT and QUERY skipped, but got %v", skipped)
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/generator"
+	"github.com/pingcap/go-ycsb/pkg/measurement"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+	"github.com/pingcap/go-ycsb/pkg/util"
+	"github.com/pingcap/go-ycsb/pkg/ycsb"
+)
+
+const timeSeriesStateKey = contextKey("timeseries")
+
+// metricField is the field holding the metric of a data point, the tags are
+// held by the fields of the tag keys.
+const metricField = "metric"
+
+type timeSeriesState struct {
+	r *rand.Rand
+}
+
+// series is a metric with a value of every tag key.
+type series struct {
+	metric int64
+	tags   []int64
+}
+
+// timeSeries is the time series workload modeled on TimeSeriesWorkload of the
+// Java YCSB. A series is a metric of metriccount with a value of every tag
+// key, whose cardinalities are given by tagcardinality. A data point of a
+// series is keyed by the series and its timestamp, so the points of a series
+// are ordered by the timestamps for the time range scans.
+//
+// The load inserts the data points of all the series at every timestamp from
+// timestampstart in turn, and the inserts of the run continue with the later
+// timestamps. The reads get a point of a loaded timestamp, and the scans get
+// querytimespan points since a loaded timestamp, which are aggregated by
+// groupbyfunction if it is set.
+type timeSeries struct {
+	p *properties.Properties
+
+	table             string
+	metricCount       int64
+	cardinalities     []int64
+	seriesCount       int64
+	timestampKey      string
+	valueKey          string
+	timestampStart    int64
+	timestampInterval int64
+	queryTimeSpan     int64
+	groupByFunction   string
+
+	metricChooser     ycsb.Generator
+	tagChoosers       []ycsb.Generator
+	valueGenerator    ycsb.Generator
+	operationChooser  *generator.Discrete
+	keySequence       ycsb.Generator
+	transactionInsert *generator.AcknowledgedCounter
+}
+
+// Load implements the Workload Load interface.
+func (t *timeSeries) Load(ctx context.Context, db ycsb.DB, totalCount int64) error {
+	return nil
+}
+
+// InitThread implements the Workload InitThread interface.
+func (t *timeSeries) InitThread(ctx context.Context, threadID int, _ int) context.Context {
+	state := &timeSeriesState{r: util.NewThreadRand(t.p, "timeseries", threadID)}
+	return context.WithValue(ctx, timeSeriesStateKey, state)
+}
+
+// CleanupThread implements the Workload CleanupThread interface.
+func (t *timeSeries) CleanupThread(_ context.Context) {
+}
+
+// Close implements the Workload Close interface.
+func (t *timeSeries) Close() error {
+	return nil
+}
+
+func tagKey(i int) string {
+	return fmt.Sprintf("TAG%d", i)
+}
+
+// seriesAt returns the n-th series, the tag values of the last tag key change
+// the fastest.
+func (t *timeSeries) seriesAt(n int64) series {
+	s := series{tags: make([]int64, len(t.cardinalities))}
+	for i := len(t.cardinalities) - 1; i >= 0; i-- {
+		s.tags[i] = n % t.cardinalities[i]
+		n /= t.cardinalities[i]
+	}
+	s.metric = n % t.metricCount
+	return s
+}
+
+// pointAt returns the series and the timestamp of the n-th data point.
+func (t *timeSeries) pointAt(n int64) (series, int64) {
+	return t.seriesAt(n % t.seriesCount), t.timestampStart + n/t.seriesCount*t.timestampInterval
+}
+
+func (t *timeSeries) chooseSeries(state *timeSeriesState) series {
+	s := series{metric: t.metricChooser.Next(state.r), tags: make([]int64, len(t.tagChoosers))}
+	for i, chooser := range t.tagChoosers {
+		s.tags[i] = chooser.Next(state.r)
+	}
+	return s
+}
+
+// chooseTimestamp chooses a timestamp whose data points of all the series
+// are inserted.
+func (t *timeSeries) chooseTimestamp(state *timeSeriesState) int64 {
+	last := (t.transactionInsert.Last()+1)/t.seriesCount - 1
+	if last <= 0 {
+		return t.timestampStart
+	}
+	return t.timestampStart + state.r.Int63n(last+1)*t.timestampInterval
+}
+
+func (t *timeSeries) buildKeyName(s series, timestamp int64) string {
+	var b strings.Builder
+	b.WriteString("metric")
+	b.WriteString(strconv.FormatInt(s.metric, 10))
+	for i, tag := range s.tags {
+		b.WriteByte(',')
+		b.WriteString(tagKey(i))
+		b.WriteByte('=')
+		b.WriteString(strconv.FormatInt(tag, 10))
+	}
+	// the timestamps are padded to keep the points of a series in order.
+	fmt.Fprintf(&b, "@%019d", timestamp)
+	return b.String()
+}
+
+func (t *timeSeries) buildValues(state *timeSeriesState, s series, timestamp int64) map[string][]byte {
+	values := make(map[string][]byte, len(s.tags)+3)
+	values[metricField] = []byte(strconv.FormatInt(s.metric, 10))
+	for i, tag := range s.tags {
+		values[tagKey(i)] = []byte(strconv.FormatInt(tag, 10))
+	}
+	values[t.timestampKey] = []byte(strconv.FormatInt(timestamp, 10))
+	values[t.valueKey] = []byte(strconv.FormatInt(t.valueGenerator.Next(state.r), 10))
+	return values
+}
+
+// inSeries returns whether the scanned row is a point of the series.
+func inSeries(s series, row map[string][]byte) bool {
+	if string(row[metricField]) != strconv.FormatInt(s.metric, 10) {
+		return false
+	}
+	for i, tag := range s.tags {
+		if string(row[tagKey(i)]) != strconv.FormatInt(tag, 10) {
+			return false
+		}
+	}
+	return true
+}
+
+// DoInsert implements the Workload DoInsert interface.
+func (t *timeSeries) DoInsert(ctx context.Context, db ycsb.DB) error {
+	state := ctx.Value(timeSeriesStateKey).(*timeSeriesState)
+	s, timestamp := t.pointAt(t.keySequence.Next(state.r))
+	return db.Insert(ctx, t.table, t.buildKeyName(s, timestamp), t.buildValues(state, s, timestamp))
+}
+
+// DoBatchInsert implements the Workload DoBatchInsert interface.
+func (t *timeSeries) DoBatchInsert(ctx context.Context, batchSize int, db ycsb.DB) error {
+	batchDB, ok := db.(ycsb.BatchDB)
+	if !ok {
+		return fmt.Errorf("the %T doesn't implement the batchDB interface", db)
+	}
+	state := ctx.Value(timeSeriesStateKey).(*timeSeriesState)
+
+	keys := make([]string, batchSize)
+	values := make([]map[string][]byte, batchSize)
+	for i := 0; i < batchSize; i++ {
+		s, timestamp := t.pointAt(t.keySequence.Next(state.r))
+		keys[i] = t.buildKeyName(s, timestamp)
+		values[i] = t.buildValues(state, s, timestamp)
+	}
+	return batchDB.BatchInsert(ctx, t.table, keys, values)
+}
+
+// DoTransaction implements the Workload DoTransaction interface.
+func (t *timeSeries) DoTransaction(ctx context.Context, db ycsb.DB) error {
+	state := ctx.Value(timeSeriesStateKey).(*timeSeriesState)
+
+	switch operationType(t.operationChooser.Next(state.r)) {
+	case insert:
+		return t.doTransactionInsert(ctx, db, state)
+	case scan:
+		return t.doTransactionScan(ctx, db, state)
+	default:
+		return t.doTransactionRead(ctx, db, state)
+	}
+}
+
+// DoBatchTransaction implements the Workload DoBatchTransaction interface,
+// the operations of the batch run one by one.
+func (t *timeSeries) DoBatchTransaction(ctx context.Context, batchSize int, db ycsb.DB) error {
+	for i := 0; i < batchSize; i++ {
+		if err := t.DoTransaction(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *timeSeries) doTransactionInsert(ctx context.Context, db ycsb.DB, state *timeSeriesState) error {
+	n := t.transactionInsert.Next(state.r)
+	defer t.transactionInsert.Acknowledge(n)
+
+	s, timestamp := t.pointAt(n)
+	return db.Insert(ctx, t.table, t.buildKeyName(s, timestamp), t.buildValues(state, s, timestamp))
+}
+
+func (t *timeSeries) doTransactionRead(ctx context.Context, db ycsb.DB, state *timeSeriesState) error {
+	s := t.chooseSeries(state)
+	_, err := db.Read(ctx, t.table, t.buildKeyName(s, t.chooseTimestamp(state)), nil)
+	return err
+}
+
+// doTransactionScan scans the points of a series in a time range, and
+// aggregates their values if groupbyfunction is set, which is measured as
+// AGGREGATE including the scan.
+func (t *timeSeries) doTransactionScan(ctx context.Context, db ycsb.DB, state *timeSeriesState) (err error) {
+	s := t.chooseSeries(state)
+	startTime := t.chooseTimestamp(state)
+
+	start := time.Now()
+	if t.groupByFunction != "" {
+		defer func() {
+			// don't report the operation interrupted by the stopping run
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+			measurement.MeasureContext(ctx, "AGGREGATE", start, time.Now().Sub(start))
+		}()
+	}
+
+	points, err := t.scanPoints(ctx, db, s, startTime)
+	if err != nil {
+		return err
+	}
+	if t.groupByFunction != "" {
+		aggregate(t.groupByFunction, points)
+	}
+	return nil
+}
+
+// scanPoints returns the values of the points of the series in the time range
+// of querytimespan since startTime.
+func (t *timeSeries) scanPoints(ctx context.Context, db ycsb.DB, s series, startTime int64) ([]int64, error) {
+	endTime := startTime + t.queryTimeSpan*t.timestampInterval
+	rows, err := db.Scan(ctx, t.table, t.buildKeyName(s, startTime), int(t.queryTimeSpan), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the scan may run into the next series if the range is after the last
+	// inserted point.
+	var points []int64
+	for _, row := range rows {
+		if !inSeries(s, row) {
+			break
+		}
+		timestamp, err := strconv.ParseInt(string(row[t.timestampKey]), 10, 64)
+		if err != nil || timestamp >= endTime {
+			break
+		}
+		value, err := strconv.ParseInt(string(row[t.valueKey]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q of the point at %d", row[t.valueKey], timestamp)
+		}
+		points = append(points, value)
+	}
+	return points, nil
+}
+
+// aggregate aggregates the values by the function, one of sum, count, avg,
+// min and max.
+func aggregate(function string, values []int64) float64 {
+	if function == "count" {
+		return float64(len(values))
+	}
+	if len(values) == 0 {
+		return 0
+	}
+
+	var sum int64
+	minValue, maxValue := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, v := range values {
+		sum += v
+		if v < minValue {
+			minValue = v
+		}
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+
+	switch function {
+	case "sum":
+		return float64(sum)
+	case "avg":
+		return float64(sum) / float64(len(values))
+	case "min":
+		return float64(minValue)
+	default:
+		return float64(maxValue)
+	}
+}
+
+// newTimeSeriesChooser chooses the numbers in [0, n) by the distribution.
+func newTimeSeriesChooser(distribution string, n int64) (ycsb.Generator, error) {
+	if n == 1 {
+		return generator.NewConstant(0), nil
+	}
+
+	switch distribution {
+	case "uniform":
+		return generator.NewUniform(0, n-1), nil
+	case "zipfian":
+		return generator.NewZipfianWithRange(0, n-1, generator.ZipfianConstant), nil
+	default:
+		return nil, fmt.Errorf("unsupported distribution %s", distribution)
+	}
+}
+
+type timeSeriesCreator struct {
+}
+
+// Create implements the WorkloadCreator Create interface.
+func (timeSeriesCreator) Create(p *properties.Properties) (ycsb.Workload, error) {
+	t := &timeSeries{
+		p:                 p,
+		table:             p.GetString(prop.TableName, prop.TableNameDefault),
+		metricCount:       p.GetInt64(prop.MetricCount, prop.MetricCountDefault),
+		timestampKey:      p.GetString(prop.TimestampKey, prop.TimestampKeyDefault),
+		valueKey:          p.GetString(prop.ValueKey, prop.ValueKeyDefault),
+		timestampStart:    p.GetInt64(prop.TimestampStart, prop.TimestampStartDefault),
+		timestampInterval: p.GetInt64(prop.TimestampInterval, prop.TimestampIntervalDefault),
+		queryTimeSpan:     p.GetInt64(prop.QueryTimeSpan, prop.QueryTimeSpanDefault),
+		groupByFunction:   strings.ToLower(p.GetString(prop.GroupByFunction, "")),
+	}
+	if t.metricCount < 1 || t.timestampInterval < 1 || t.queryTimeSpan < 1 {
+		return nil, fmt.Errorf("%s, %s and %s must be positive", prop.MetricCount, prop.TimestampInterval, prop.QueryTimeSpan)
+	}
+	switch t.groupByFunction {
+	case "", "sum", "count", "avg", "min", "max":
+	default:
+		return nil, fmt.Errorf("unsupported %s %s", prop.GroupByFunction, t.groupByFunction)
+	}
+
+	requestDistrib := p.GetString(prop.RequestDistribution, prop.RequestDistributionDefault)
+	var err error
+	if t.metricChooser, err = newTimeSeriesChooser(requestDistrib, t.metricCount); err != nil {
+		return nil, err
+	}
+	t.seriesCount = t.metricCount
+	for _, s := range strings.Split(p.GetString(prop.TagCardinality, prop.TagCardinalityDefault), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		cardinality, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || cardinality < 1 {
+			return nil, fmt.Errorf("invalid %s %s", prop.TagCardinality, s)
+		}
+		chooser, err := newTimeSeriesChooser(requestDistrib, cardinality)
+		if err != nil {
+			return nil, err
+		}
+		t.cardinalities = append(t.cardinalities, cardinality)
+		t.tagChoosers = append(t.tagChoosers, chooser)
+		t.seriesCount *= cardinality
+	}
+
+	maxValue := p.GetInt64(prop.MaxValue, prop.MaxValueDefault)
+	switch valueDistrib := p.GetString(prop.ValueDistribution, prop.ValueDistributionDefault); valueDistrib {
+	case "constant":
+		t.valueGenerator = generator.NewConstant(maxValue)
+	default:
+		if t.valueGenerator, err = newTimeSeriesChooser(valueDistrib, maxValue+1); err != nil {
+			return nil, err
+		}
+	}
+
+	t.operationChooser = generator.NewDiscrete()
+	for _, op := range []struct {
+		name string
+		def  float64
+		op   operationType
+	}{
+		{prop.ReadProportion, prop.ReadProportionDefault, read},
+		{prop.InsertProportion, prop.InsertProportionDefault, insert},
+		{prop.ScanProportion, prop.ScanProportionDefault, scan},
+	} {
+		if proportion := p.GetFloat64(op.name, op.def); proportion > 0 {
+			t.operationChooser.Add(proportion, int64(op.op))
+		}
+	}
+
+	insertStart := p.GetInt64(prop.InsertStart, prop.InsertStartDefault)
+	t.keySequence = generator.NewCounter(insertStart)
+	t.transactionInsert = generator.NewAcknowledgedCounter(p.GetInt64(prop.RecordCount, prop.RecordCountDefault))
+	return t, nil
+}
+
+func init() {
+	ycsb.RegisterWorkloadCreator("timeseries", timeSeriesCreator{})
+	ycsb.RegisterWorkloadCreator("site.ycsb.workloads.TimeSeriesWorkload", timeSeriesCreator{})
+}
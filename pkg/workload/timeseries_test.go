@@ -0,0 +1,73 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties"
+	"github.com/pingcap/go-ycsb/pkg/prop"
+)
+
+func TestTimeSeries(t *testing.T) {
+	p := properties.NewProperties()
+	// 2 metrics with 2*3 tag values are 12 series, 20 points each.
+	p.Set(prop.RecordCount, "240")
+	p.Set(prop.MetricCount, "2")
+	p.Set(prop.TagCardinality, "2,3")
+	p.Set(prop.TimestampStart, "1000")
+	p.Set(prop.TimestampInterval, "10")
+	p.Set(prop.QueryTimeSpan, "5")
+	p.Set(prop.ReadProportion, "0.4")
+	p.Set(prop.InsertProportion, "0.2")
+	p.Set(prop.ScanProportion, "0.4")
+	p.Set(prop.GroupByFunction, "avg")
+	p.Set(prop.RandomSeed, "1")
+
+	w, ctx := newTestWorkload(t, timeSeriesCreator{}, p)
+	ts := w.(*timeSeries)
+	db := newMemDB()
+	doInserts(t, w, ctx, db, 240)
+	if len(db.rows) != 240 {
+		t.Fatalf("want 240 points, but got %d", len(db.rows))
+	}
+	if key := ts.buildKeyName(ts.seriesAt(11), 1190); db.rows[key] == nil {
+		t.Fatalf("the last point %s is not loaded", key)
+	}
+
+	s := ts.seriesAt(5)
+	points, err := ts.scanPoints(ctx, db, s, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 5 {
+		t.Fatalf("want 5 points in the time range, but got %d", len(points))
+	}
+	// the range after the last point doesn't run into the next series.
+	if points, err = ts.scanPoints(ctx, db, s, 1170); err != nil || len(points) != 3 {
+		t.Fatalf("want 3 points at the end of the series, but got %d, %v", len(points), err)
+	}
+
+	doTransactions(t, w, ctx, db, 100)
+	if len(db.rows) <= 240 {
+		t.Fatal("the inserts of the run should add the later points")
+	}
+
+	if v := aggregate("avg", []int64{1, 2, 6}); v != 3 {
+		t.Fatalf("want avg 3, but got %v", v)
+	}
+	if v := aggregate("max", []int64{1, 2, 6}); v != 6 {
+		t.Fatalf("want max 6, but got %v", v)
+	}
+}
@@ -15,6 +15,7 @@ package ycsb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/magiconair/properties"
@@ -102,6 +103,53 @@ type AnalyzeDB interface {
 	Analyze(ctx context.Context, table string) error
 }
 
+// TransactionalDB is the interface for the DB that can run several operations
+// in one transaction. The transaction is carried by the context returned by
+// Begin, the operations with the context run in the transaction.
+type TransactionalDB interface {
+	// Begin begins a transaction, and returns the context of it.
+	Begin(ctx context.Context) (context.Context, error)
+
+	// Commit commits the transaction of the context returned by Begin.
+	Commit(ctx context.Context) error
+
+	// Abort rolls back the transaction of the context returned by Begin.
+	Abort(ctx context.Context) error
+}
+
+// ErrNotTransactional is returned by Begin of a middleware whose wrapped DB
+// doesn't implement TransactionalDB.
+var ErrNotTransactional = errors.New("the DB doesn't support transactions")
+
+// ErrNoTransaction is returned by Commit and Abort if the context has no
+// transaction begun by Begin.
+var ErrNoTransaction = errors.New("no transaction is begun in the context")
+
+// QueryDB is the interface for the DB that can query the records by a
+// predicate on a non-key field, which is served by a secondary index of the
+// field.
+type QueryDB interface {
+	// CreateIndex creates a secondary index on a field, it does nothing if
+	// the index exists.
+	// table: The name of the table.
+	// field: The field to index.
+	CreateIndex(ctx context.Context, table string, field string) error
+
+	// Query reads the records whose field is in [low, high], the predicate is
+	// an equality if low and high are the same.
+	// table: The name of the table.
+	// field: The field of the predicate.
+	// low: The lowest value of the field, inclusive.
+	// high: The highest value of the field, inclusive.
+	// count: The maximum number of records to read.
+	// fields: The list of fields to read, nil|empty for reading all.
+	Query(ctx context.Context, table string, field string, low []byte, high []byte, count int, fields []string) ([]map[string][]byte, error)
+}
+
+// ErrNotQueryable is returned by a middleware whose wrapped DB doesn't
+// implement QueryDB.
+var ErrNotQueryable = errors.New("the DB doesn't support queries")
+
 var dbCreators = map[string]DBCreator{}
 
 // RegisterDBCreator registers a creator for the database
@@ -118,3 +166,27 @@ func RegisterDBCreator(name string, creator DBCreator) {
 func GetDBCreator(name string) DBCreator {
 	return dbCreators[name]
 }
+
+// DBMiddlewareCreator creates a middleware which wraps a database layer, e.g.
+// to measure, retry or log its operations. The middleware should implement
+// BatchDB and AnalyzeDB too, no matter whether the wrapped DB does.
+type DBMiddlewareCreator interface {
+	Create(p *properties.Properties, db DB) (DB, error)
+}
+
+var dbMiddlewareCreators = map[string]DBMiddlewareCreator{}
+
+// RegisterDBMiddlewareCreator registers a creator for the middleware
+func RegisterDBMiddlewareCreator(name string, creator DBMiddlewareCreator) {
+	_, ok := dbMiddlewareCreators[name]
+	if ok {
+		panic(fmt.Sprintf("duplicate register database middleware %s", name))
+	}
+
+	dbMiddlewareCreators[name] = creator
+}
+
+// GetDBMiddlewareCreator gets the DBMiddlewareCreator for the middleware
+func GetDBMiddlewareCreator(name string) DBMiddlewareCreator {
+	return dbMiddlewareCreators[name]
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ycsb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+)
+
+// ErrorClass is the class of a failed operation.
+type ErrorClass int
+
+// Error classes.
+const (
+	// ErrorOther is any error which is not classified.
+	ErrorOther ErrorClass = iota
+	// ErrorNotFound means the record doesn't exist.
+	ErrorNotFound
+	// ErrorTimeout means the operation timed out.
+	ErrorTimeout
+	// ErrorConflict means the operation conflicted with another one and
+	// can be retried, e.g. a write conflict or a deadlock.
+	ErrorConflict
+	// ErrorThrottled means the database rejected the operation because it
+	// is overloaded.
+	ErrorThrottled
+)
+
+var errorClassNames = []string{"OTHER", "NOT_FOUND", "TIMEOUT", "CONFLICT", "THROTTLED"}
+
+// String returns the name of the class, which is used in the measurements.
+func (c ErrorClass) String() string {
+	if c < 0 || int(c) >= len(errorClassNames) {
+		return errorClassNames[ErrorOther]
+	}
+	return errorClassNames[c]
+}
+
+type classifiedError struct {
+	class ErrorClass
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// WrapError annotates err with the class, it returns nil if err is nil.
+// The DB bindings use it to classify their errors.
+func WrapError(class ErrorClass, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// ErrorClassifier is the interface for the DB that classifies its errors
+// which are not wrapped by WrapError.
+type ErrorClassifier interface {
+	// ClassifyError returns the class of the error returned by the DB.
+	ClassifyError(err error) ErrorClass
+}
+
+// ClassifyError returns the class of err. It returns the class given to
+// WrapError if err wraps one, otherwise it recognizes the timeouts of the
+// context and the network, and returns ErrorOther for the rest.
+func ClassifyError(err error) ErrorClass {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrorTimeout
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return ErrorTimeout
+	}
+	return ErrorOther
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ycsb
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Result is the final result of a run with its metadata.
+type Result struct {
+	Label      string            `json:"label"`
+	DB         string            `json:"db"`
+	Workload   string            `json:"workload"`
+	Command    string            `json:"command"`
+	Version    string            `json:"version"`
+	StartTime  time.Time         `json:"start_time"`
+	EndTime    time.Time         `json:"end_time"`
+	Properties map[string]string `json:"properties"`
+	// Operations are the metrics of every operation keyed by the metric names,
+	// such as COUNT and PER99TH. It is empty if the measurement type keeps
+	// no histograms, e.g. raw, whose output has the measurements instead.
+	Operations map[string]map[string]interface{} `json:"operations,omitempty"`
+}
+
+// Exporter writes the final result of a run in a machine-readable format.
+type Exporter interface {
+	Export(w io.Writer, result *Result) error
+}
+
+var exporters = map[string]Exporter{}
+
+// RegisterExporter registers an exporter
+func RegisterExporter(name string, exporter Exporter) {
+	_, ok := exporters[name]
+	if ok {
+		panic(fmt.Sprintf("duplicate register exporter %s", name))
+	}
+
+	exporters[name] = exporter
+}
+
+// GetExporter gets the exporter
+func GetExporter(name string) Exporter {
+	return exporters[name]
+}
@@ -23,7 +23,8 @@ type Measurer interface {
 	// Measure measures the latency of an operation.
 	Measure(op string, start time.Time, latency time.Duration)
 
-	// Summary writes a summary of the current measurement results to stdout.
+	// Summary writes a summary of the measurement results since the last
+	// summary to stdout.
 	Summary()
 
 	// GenerateExtendedOutputs is called at the end of the benchmark
@@ -0,0 +1,32 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ycsb
+
+// TraceOp is an operation recorded in a trace file, which holds one JSON
+// object per line.
+type TraceOp struct {
+	// Time is the time since the recording started in us.
+	Time int64 `json:"t"`
+	// Op is the name of the operation, e.g. READ or BATCH_INSERT.
+	Op    string `json:"op"`
+	Table string `json:"table"`
+	// Keys are the keys of the operation, or the start key of a scan.
+	Keys []string `json:"keys"`
+	// Fields are the fields to read or scan, nil for all.
+	Fields []string `json:"fields,omitempty"`
+	// Count is the number of the records to scan.
+	Count int `json:"count,omitempty"`
+	// Sizes are the sizes of the values of every key to insert or update.
+	Sizes []map[string]int `json:"sizes,omitempty"`
+}
@@ -15,6 +15,7 @@ package ycsb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/magiconair/properties"
@@ -25,6 +26,11 @@ type WorkloadCreator interface {
 	Create(p *properties.Properties) (Workload, error)
 }
 
+// ErrWorkloadFinished is returned by DoInsert, DoBatchInsert, DoTransaction
+// and DoBatchTransaction if the workload has no more operations, e.g. at
+// the end of a replayed trace, the worker stops then.
+var ErrWorkloadFinished = errors.New("workload finished")
+
 // Workload defines different workload for YCSB.
 type Workload interface {
 	// Close closes the workload.
@@ -53,6 +59,13 @@ type Workload interface {
 	DoBatchTransaction(ctx context.Context, batchSize int, db DB) error
 }
 
+// Validator is the interface for the workload that can validate the data
+// after the run, e.g. to check an invariant kept by its transactions.
+type Validator interface {
+	// Validate checks the data in DB, and returns an error if it is invalid.
+	Validate(ctx context.Context, db DB) error
+}
+
 var workloadCreators = map[string]WorkloadCreator{}
 
 // RegisterWorkloadCreator registers a creator for the workload